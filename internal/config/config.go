@@ -5,6 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"yt-dlp-web/internal/updater"
 )
 
 // Config holds all application configuration loaded from environment variables.
@@ -16,6 +20,57 @@ type Config struct {
 	MaxConcurrent int
 	YtDlpPath     string
 	DefaultArgs   []string
+	// UsePython selects the python3-wrapper exec path in backend_ytdlp.go
+	// (running `<dir of YtDlpPath>/python3 <YtDlpPath> <args>` instead of
+	// exec'ing YtDlpPath directly) — Android ships yt-dlp as a libytdlp.so
+	// loaded by a bundled python3 interpreter rather than a standalone
+	// native binary, which is exactly what ResolveYtDlpPath resolves to.
+	UsePython bool
+
+	// Aria2Enabled turns on Aria2Backend for "auto" backend selection and
+	// explicit "backend=aria2" requests; Aria2RPCURL/Aria2Secret point at an
+	// already-running aria2c --enable-rpc daemon (this project doesn't spawn one).
+	Aria2Enabled bool
+	Aria2RPCURL  string
+	Aria2Secret  string
+	// HTTPBackendEnabled turns on HTTPBackend, the plain net/http downloader
+	// for direct-file URLs that don't need yt-dlp at all.
+	HTTPBackendEnabled bool
+
+	// TaskStorePath is the BoltDB file Manager persists task state to, so
+	// queued/paused/running jobs survive a restart.
+	TaskStorePath string
+	// AutoResume re-queues tasks that were StatusRunning when the process
+	// last stopped (crash or restart) instead of leaving them StatusPaused.
+	AutoResume bool
+	// RetainCompleted is how long a completed/failed/cancelled task is kept
+	// before Manager's GC pass removes it; zero disables GC entirely.
+	RetainCompleted time.Duration
+
+	// MaxBytesPerSec is the manager-wide bandwidth cap shared by every
+	// task (0 disables it); see download.Manager.SetGlobalLimit for the
+	// runtime override.
+	MaxBytesPerSec int64
+
+	// StaticCacheMaxAge is the Cache-Control max-age applied to the
+	// embedded/filesystem-served SPA bundle.
+	StaticCacheMaxAge time.Duration
+	// TasksCacheTTL is how long a GET /api/tasks response may be served
+	// from cache when no task is currently running; it's bypassed
+	// entirely while a task is active or API_KEY auth is enabled, so a
+	// stale progress snapshot or a cross-tenant leak can't happen.
+	TasksCacheTTL time.Duration
+
+	// RateLimitRPM/RateLimitBurst cap how often one caller (keyed by
+	// X-API-Key if present, else client IP) may hit the submission
+	// endpoints — yt-dlp jobs are expensive, so an unauthenticated
+	// exposed instance needs this on by default. 0 disables it.
+	RateLimitRPM   int
+	RateLimitBurst int
+	// TrustedProxies lets RateLimitRPM key by the real client IP from
+	// X-Forwarded-For when requests arrive via a reverse proxy; empty
+	// means trust only the immediate peer address (fiber's default).
+	TrustedProxies []string
 }
 
 // Load reads environment variables and returns a populated Config.
@@ -27,12 +82,56 @@ func Load() *Config {
 		StaticDir:     envOr("STATIC_DIR", "./static"),
 		MaxConcurrent: envOrInt("MAX_CONCURRENT", 2),
 		YtDlpPath:     envOr("YTDLP_PATH", "yt-dlp"),
+
+		Aria2Enabled:       os.Getenv("ARIA2_RPC_URL") != "",
+		Aria2RPCURL:        envOr("ARIA2_RPC_URL", "http://127.0.0.1:6800/jsonrpc"),
+		Aria2Secret:        os.Getenv("ARIA2_SECRET"),
+		HTTPBackendEnabled: envOr("HTTP_BACKEND", "on") != "off",
+
+		AutoResume:      envOr("AUTO_RESUME", "on") != "off",
+		RetainCompleted: envOrDuration("RETAIN_COMPLETED", 7*24*time.Hour),
+		MaxBytesPerSec:  envOrInt64("MAX_BYTES_PER_SEC", 0),
+
+		StaticCacheMaxAge: envOrDuration("STATIC_CACHE_MAX_AGE", time.Hour),
+		TasksCacheTTL:     envOrDuration("TASKS_CACHE_TTL", time.Second),
+
+		RateLimitRPM:   envOrIntAllowZero("RATE_LIMIT_RPM", 30),
+		RateLimitBurst: envOrInt("RATE_LIMIT_BURST", 10),
+		TrustedProxies: envOrList("TRUSTED_PROXIES"),
 	}
+	cfg.TaskStorePath = envOr("TASK_DB_PATH", filepath.Join(cfg.ConfigDir, "tasks.db"))
 
 	if cfg.YtDlpPath == "yt-dlp" {
 		cfg.YtDlpPath = ResolveYtDlpPath(cfg.YtDlpPath)
 	}
 
+	// YTDLP_AUTO_UPDATE=on|off|stable|nightly|master lets operators track or
+	// pin a yt-dlp release instead of rebuilding the container every time a
+	// site extractor breaks upstream. YTDLP_VERSION pins an exact tag.
+	if enabled, channel := updater.ParseAutoUpdate(os.Getenv("YTDLP_AUTO_UPDATE")); enabled {
+		if ch := os.Getenv("YTDLP_CHANNEL"); ch != "" {
+			channel = updater.Channel(ch)
+		}
+		path, err := updater.EnsureUpToDate(updater.Options{
+			ConfigDir: cfg.ConfigDir,
+			Channel:   channel,
+			Version:   os.Getenv("YTDLP_VERSION"),
+		})
+		if err != nil {
+			log.Printf("[config] yt-dlp auto-update failed, keeping existing binary: %v", err)
+		} else {
+			cfg.YtDlpPath = path
+		}
+	} else if managed := updater.ManagedPath(cfg.ConfigDir); fileExists(managed) {
+		// Prefer a previously updater-managed binary over a bare PATH lookup.
+		cfg.YtDlpPath = managed
+	}
+
+	// YTDLP_USE_PYTHON overrides the auto-detected default (true when
+	// YtDlpPath resolved to a .so, false otherwise) for the rare case where
+	// that heuristic guesses wrong.
+	cfg.UsePython = envOrBool("YTDLP_USE_PYTHON", strings.HasSuffix(cfg.YtDlpPath, ".so"))
+
 	if err := os.MkdirAll(cfg.DownloadDir, 0o755); err != nil {
 		log.Fatalf("failed to create download dir %s: %v", cfg.DownloadDir, err)
 	}
@@ -59,6 +158,11 @@ func Load() *Config {
 	return cfg
 }
 
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -163,3 +267,70 @@ func envOrInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// envOrIntAllowZero is envOrInt but lets the env var explicitly opt out with
+// "0", for knobs (like RateLimitRPM) whose non-zero default needs a way to
+// be disabled outright rather than just left unset.
+func envOrIntAllowZero(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// envOrBool parses a truthy/falsy env var ("1"/"true"/"on"/"yes" vs.
+// "0"/"false"/"off"/"no"), falling back to def when unset or unrecognized.
+func envOrBool(key string, def bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "1", "true", "on", "yes":
+		return true
+	case "0", "false", "off", "no":
+		return false
+	default:
+		return def
+	}
+}
+
+// envOrList parses a comma-separated env var into a trimmed, non-empty
+// string slice (nil if unset), the same convention YTDLP_WEB_TRACE uses.
+func envOrList(key string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// envOrInt64 parses an int64 env var (e.g. a byte count too large for int
+// on 32-bit platforms), falling back on empty, unparseable, or non-positive values.
+func envOrInt64(key string, fallback int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// envOrDuration parses a Go duration string (e.g. "72h", "0" to disable).
+func envOrDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	if v == "0" {
+		return 0
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,143 @@
+// Package cookies lets a user upload a Netscape-format cookies file so
+// yt-dlp can authenticate to sites that require login, without ever letting
+// a request point yt-dlp's --cookies flag at an arbitrary server path.
+package cookies
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MaxJarBytes bounds the size of an uploaded cookies file.
+const MaxJarBytes = 1 << 20 // 1MiB
+
+var jarIDRe = regexp.MustCompile(`^[a-f0-9]{24}$`)
+
+var sessionIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// Store manages per-session cookie jars under ConfigDir/cookies/<session-id>/.
+type Store struct {
+	baseDir string
+}
+
+// NewStore creates a Store rooted at configDir/cookies.
+func NewStore(configDir string) *Store {
+	return &Store{baseDir: filepath.Join(configDir, "cookies")}
+}
+
+// Save validates r as a Netscape-format cookies file and writes it into the
+// session's jar directory, returning a jar ID that can later be resolved
+// with Path. The session ID is trusted to already be validated by the caller
+// (e.g. derived from an authenticated session, not a raw path).
+func (s *Store) Save(sessionID string, r io.Reader) (jarID string, err error) {
+	if !sessionIDRe.MatchString(sessionID) {
+		return "", fmt.Errorf("cookies: invalid session id")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxJarBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("cookies: read upload: %w", err)
+	}
+	if len(data) > MaxJarBytes {
+		return "", fmt.Errorf("cookies: file exceeds %d byte limit", MaxJarBytes)
+	}
+	if err := validateNetscapeFormat(data); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.baseDir, sessionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("cookies: create session dir: %w", err)
+	}
+
+	jarID = newJarID()
+	path := filepath.Join(dir, jarID+".txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("cookies: write jar: %w", err)
+	}
+	return jarID, nil
+}
+
+// Path resolves a (sessionID, jarID) pair to an on-disk path, verifying the
+// jar ID is well-formed and the resolved path stays within the session's
+// jar directory. This is the only way callers obtain a filesystem path —
+// DownloadRequest.CookiesJar carries the opaque ID, never a path.
+func (s *Store) Path(sessionID, jarID string) (string, error) {
+	if !sessionIDRe.MatchString(sessionID) {
+		return "", fmt.Errorf("cookies: invalid session id")
+	}
+	if !jarIDRe.MatchString(jarID) {
+		return "", fmt.Errorf("cookies: invalid jar id")
+	}
+	dir := filepath.Join(s.baseDir, sessionID)
+	path := filepath.Join(dir, jarID+".txt")
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(absPath, absDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("cookies: jar path escapes session dir")
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return "", fmt.Errorf("cookies: jar not found: %w", err)
+	}
+	return absPath, nil
+}
+
+func newJarID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validateNetscapeFormat rejects binary content and anything that doesn't
+// look like a Netscape cookies file: the header comment, blank lines, `#`
+// comments, or 7 tab-separated fields (domain, flag, path, secure, expiry,
+// name, value) per yt-dlp/curl's documented format.
+func validateNetscapeFormat(data []byte) error {
+	for _, b := range data {
+		if b == 0 {
+			return fmt.Errorf("cookies: file contains binary (NUL byte) content")
+		}
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	sawCookieLine := false
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(strings.Split(line, "\t")) != 7 {
+			return fmt.Errorf("cookies: line does not match Netscape cookie format (want 7 tab-separated fields): %q", truncate(line, 80))
+		}
+		sawCookieLine = true
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("cookies: scan upload: %w", err)
+	}
+	if !sawCookieLine {
+		return fmt.Errorf("cookies: no cookie entries found")
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,65 @@
+package download
+
+import (
+	"context"
+
+	"yt-dlp-web/internal/config"
+)
+
+// Event is a backend-agnostic progress signal emitted while a transfer runs.
+// Backends mutate the Task's exported fields directly (same locking
+// convention as the original yt-dlp exec path) and use Event only to tell
+// the Manager when to broadcast and when the transfer is finished, so the
+// existing AddLog/ParseLine-style code didn't need to be torn apart to
+// support more than one way of fetching bytes.
+type Event struct {
+	Changed bool  // a progress field on the Task changed; caller should broadcast
+	Done    bool  // the transfer is over (success, failure, or cancellation)
+	Err     error // set on Done when the transfer failed
+}
+
+// Backend executes a single transfer — one URL, with whatever args/output
+// template the Task carries — and streams Events until it finishes or ctx
+// is cancelled. The channel must be closed when no more Events will arrive.
+type Backend interface {
+	// Name identifies the backend in logs and the "backend" API field.
+	Name() string
+	// Supports reports whether this backend can handle url at all, used by
+	// "auto" selection to skip backends that would just fail immediately.
+	Supports(url string) bool
+	// Start begins the transfer for t under ctx, reusing cfg for paths and
+	// defaults. t.mu must be used for any mutation of t's exported fields,
+	// matching every other part of this package.
+	Start(ctx context.Context, t *Task, cfg *config.Config) (<-chan Event, error)
+	// Cancel performs any out-of-band cleanup ctx cancellation alone can't —
+	// e.g. telling a remote aria2 daemon to drop a job it owns independently
+	// of our process. Backends where ctx cancellation is sufficient (yt-dlp,
+	// plain HTTP) can make this a no-op.
+	Cancel(t *Task)
+}
+
+// backendByName returns the configured backend matching name ("ytdlp",
+// "aria2", "http"), or nil if unknown or not enabled.
+func backendByName(backends []Backend, name string) Backend {
+	for _, b := range backends {
+		if b.Name() == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// selectBackend resolves the backend a Task should run on: an explicit
+// name if given and known, otherwise the first enabled backend (in
+// configured order) whose Supports(url) returns true.
+func selectBackend(backends []Backend, name, url string) Backend {
+	if name != "" && name != "auto" {
+		return backendByName(backends, name)
+	}
+	for _, b := range backends {
+		if b.Supports(url) {
+			return b
+		}
+	}
+	return nil
+}
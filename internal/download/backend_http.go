@@ -0,0 +1,203 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"yt-dlp-web/internal/config"
+)
+
+// HTTPBackend fetches a direct-URL file with net/http instead of yt-dlp,
+// for links that aren't a video-site page at all (a bare .mp4/.zip/etc) and
+// so don't need a Python process just to copy bytes. It resumes via Range
+// if a partial ".part" file from a previous attempt already exists.
+type HTTPBackend struct{}
+
+func (HTTPBackend) Name() string { return "http" }
+
+// Supports only claims URLs that look like a direct file (have a
+// recognizable extension in the path), leaving page URLs to yt-dlp — a
+// bare "http://host/video.mp4" should use this backend, "auto" selection
+// should not steal youtube.com links from YtDlpBackend.
+func (HTTPBackend) Supports(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	switch ext {
+	case ".mp4", ".mkv", ".webm", ".mov", ".m4a", ".mp3", ".zip", ".bin", ".iso":
+		return true
+	default:
+		return false
+	}
+}
+
+func (HTTPBackend) Cancel(t *Task) {}
+
+func (HTTPBackend) Start(ctx context.Context, t *Task, cfg *config.Config) (<-chan Event, error) {
+	events := make(chan Event, 8)
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		close(events)
+		return events, fmt.Errorf("invalid URL: %w", err)
+	}
+	name := filepath.Base(u.Path)
+	if name == "" || name == "/" || name == "." {
+		name = "download"
+	}
+	outPath := filepath.Join(cfg.DownloadDir, name)
+	partPath := outPath + ".part"
+
+	var startAt int64
+	if fi, err := os.Stat(partPath); err == nil {
+		startAt = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		close(events)
+		return events, err
+	}
+	if startAt > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startAt))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		close(events)
+		return events, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		close(events)
+		return events, fmt.Errorf("http backend: unexpected status %s", resp.Status)
+	}
+	// Server ignored our Range header — start over rather than appending
+	// onto bytes that don't line up.
+	if resp.StatusCode == http.StatusOK {
+		startAt = 0
+	}
+
+	total := startAt + resp.ContentLength // ContentLength is -1 if unknown
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if startAt > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		resp.Body.Close()
+		close(events)
+		return events, err
+	}
+
+	t.mu.Lock()
+	t.Status = StatusRunning
+	t.Filename = outPath
+	if t.Title == t.URL || t.Title == "" {
+		t.Title = cleanTitle(name)
+	}
+	t.UpdatedAt = time.Now()
+	t.mu.Unlock()
+	events <- Event{Changed: true}
+
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		defer f.Close()
+
+		written := startAt
+		lastReport := time.Now()
+		buf := make([]byte, 64*1024)
+		for {
+			n, readErr := resp.Body.Read(buf)
+			if n > 0 {
+				if _, werr := f.Write(buf[:n]); werr != nil {
+					t.mu.Lock()
+					t.Status = StatusFailed
+					t.Error = werr.Error()
+					t.mu.Unlock()
+					events <- Event{Done: true, Err: werr}
+					return
+				}
+				written += int64(n)
+				t.setBytesTotal(written)
+				if err := t.throttle(ctx, n); err != nil {
+					t.mu.Lock()
+					if ctx.Err() == context.Canceled {
+						if t.Status != StatusPaused {
+							t.Status = StatusCancelled
+						}
+					} else {
+						t.Status = StatusFailed
+						t.Error = err.Error()
+					}
+					t.mu.Unlock()
+					events <- Event{Done: true, Err: err}
+					return
+				}
+				if time.Since(lastReport) >= time.Second {
+					lastReport = time.Now()
+					t.mu.Lock()
+					if total > 0 {
+						t.Percent = float64(written) / float64(total) * 100
+						t.Progress = fmt.Sprintf("%.1f%%", t.Percent)
+						t.Size = humanBytes(float64(total))
+					}
+					t.UpdatedAt = time.Now()
+					t.mu.Unlock()
+					events <- Event{Changed: true}
+				}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				t.mu.Lock()
+				if ctx.Err() == context.Canceled {
+					if t.Status != StatusPaused {
+						t.Status = StatusCancelled
+					}
+				} else {
+					t.Status = StatusFailed
+					t.Error = readErr.Error()
+				}
+				t.mu.Unlock()
+				events <- Event{Done: true, Err: readErr}
+				return
+			}
+		}
+
+		f.Close()
+		if err := os.Rename(partPath, outPath); err != nil {
+			t.mu.Lock()
+			t.Status = StatusFailed
+			t.Error = err.Error()
+			t.mu.Unlock()
+			events <- Event{Done: true, Err: err}
+			return
+		}
+
+		t.mu.Lock()
+		t.Status = StatusCompleted
+		t.Progress = "100%"
+		t.Percent = 100
+		t.Filename = outPath
+		t.UpdatedAt = time.Now()
+		t.mu.Unlock()
+		events <- Event{Done: true}
+	}()
+
+	return events, nil
+}
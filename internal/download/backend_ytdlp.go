@@ -0,0 +1,184 @@
+package download
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yt-dlp-web/internal/config"
+	"yt-dlp-web/internal/logging"
+)
+
+// ytdlpLog is the "download.ytdlp" subsystem logger — package-level since
+// YtDlpBackend is a value type with no Manager reference to hang a logger off.
+var ytdlpLog = logging.Named("download.ytdlp")
+
+// YtDlpBackend runs the actual yt-dlp binary — this is the original (and
+// still default) transfer path, now behind the Backend interface so
+// Aria2Backend/HTTPBackend can sit next to it.
+type YtDlpBackend struct{}
+
+func (YtDlpBackend) Name() string { return "ytdlp" }
+
+// Supports is true for everything: yt-dlp has hundreds of extractors plus a
+// generic one, so it's always a valid (if not always fastest) choice.
+func (YtDlpBackend) Supports(url string) bool { return true }
+
+// Cancel is a no-op: the yt-dlp child process is killed by cancelling the
+// context Start was given, same as before this backend existed.
+func (YtDlpBackend) Cancel(t *Task) {}
+
+func (YtDlpBackend) Start(ctx context.Context, t *Task, cfg *config.Config) (<-chan Event, error) {
+	events := make(chan Event, 8)
+
+	// Build args: defaults + user args + URL.
+	// If user specified -o, skip the default -o from DefaultArgs.
+	hasUserOutput := false
+	for _, a := range t.Args {
+		if a == "-o" || a == "--output" {
+			hasUserOutput = true
+			break
+		}
+	}
+	args := make([]string, 0, len(cfg.DefaultArgs)+len(t.Args)+1)
+	for i := 0; i < len(cfg.DefaultArgs); i++ {
+		if cfg.DefaultArgs[i] == "-o" && hasUserOutput && i+1 < len(cfg.DefaultArgs) {
+			i++ // skip -o and its value
+			continue
+		}
+		args = append(args, cfg.DefaultArgs[i])
+	}
+	args = append(args, t.Args...)
+	// yt-dlp's own process has no way to change its rate limit mid-run, so
+	// unlike HTTPBackend's live token bucket this is resolved once at
+	// start — the tighter of the task's own cap and the global one —
+	// and only takes effect on the next retry if changed at runtime.
+	if limit := effectiveLimitRate(t, cfg); limit > 0 {
+		args = append(args, "--limit-rate", strconv.FormatInt(limit, 10))
+	}
+	args = append(args, t.URL)
+
+	if strings.HasPrefix(cfg.YtDlpPath, "NOT_FOUND|") {
+		close(events)
+		return events, fmt.Errorf("YT-DLP NOT FOUND!\n\n%s", cfg.YtDlpPath)
+	}
+	if _, err := os.Stat(cfg.YtDlpPath); os.IsNotExist(err) {
+		if _, lookErr := exec.LookPath(cfg.YtDlpPath); lookErr != nil {
+			close(events)
+			return events, fmt.Errorf("yt-dlp not found at %s or in PATH", cfg.YtDlpPath)
+		}
+	}
+
+	ytdlpLog.Debug("starting transfer", "ytdlp_path", cfg.YtDlpPath, "use_python", cfg.UsePython, "args", args)
+
+	// Ensure download & cache dirs exist before every execution.
+	// On Android the dirs may vanish after startup (storage cleanup, permission changes).
+	os.MkdirAll(cfg.DownloadDir, 0o755)
+	os.MkdirAll(filepath.Join(cfg.ConfigDir, "cache"), 0o755)
+
+	var cmd *exec.Cmd
+	if cfg.UsePython {
+		// Python mode: find python3 in the same directory as the script.
+		scriptDir := filepath.Dir(cfg.YtDlpPath)
+		pythonPath := filepath.Join(scriptDir, "python3")
+		if _, err := os.Stat(pythonPath); os.IsNotExist(err) {
+			pythonPath = filepath.Join(scriptDir, "..", "python3")
+		}
+		ytdlpLog.Debug("using python wrapper", "python_path", pythonPath, "script", cfg.YtDlpPath)
+		cmd = exec.CommandContext(ctx, pythonPath, append([]string{cfg.YtDlpPath}, args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, cfg.YtDlpPath, args...)
+	}
+	cmd.Dir = cfg.DownloadDir // yt-dlp resolves relative -o paths from cwd
+	cmd.Env = append(os.Environ(),
+		"XDG_CACHE_HOME="+cfg.ConfigDir+"/cache",
+		"XDG_CONFIG_HOME="+cfg.ConfigDir,
+		"HOME="+cfg.ConfigDir,
+	)
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		close(events)
+		return events, err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		close(events)
+		return events, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		close(events)
+		if strings.HasPrefix(cfg.YtDlpPath, "NOT_FOUND|") {
+			return events, fmt.Errorf("YT-DLP NOT FOUND!\n\nDiagnostic info:\n%s", cfg.YtDlpPath)
+		}
+		return events, err
+	}
+
+	t.mu.Lock()
+	t.cmd = cmd
+	t.mu.Unlock()
+
+	go func() {
+		defer close(events)
+
+		lines := make(chan string, 64)
+		var wg sync.WaitGroup
+		readPipe := func(r io.Reader) {
+			defer wg.Done()
+			sc := bufio.NewScanner(r)
+			sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for sc.Scan() {
+				lines <- sc.Text()
+			}
+		}
+		wg.Add(2)
+		go readPipe(stdoutPipe)
+		go readPipe(stderrPipe)
+		go func() { wg.Wait(); close(lines) }()
+
+		for line := range lines {
+			t.AddLog(line) // AddLog has its own lock
+			t.mu.Lock()
+			changed := ParseLine(line, t)
+			t.mu.Unlock()
+			if changed {
+				events <- Event{Changed: true}
+			}
+		}
+
+		waitErr := cmd.Wait()
+
+		t.mu.Lock()
+		if waitErr != nil {
+			if ctx.Err() == context.Canceled {
+				if t.Status != StatusPaused {
+					t.Status = StatusCancelled
+				}
+			} else {
+				t.Status = StatusFailed
+				t.Error = waitErr.Error()
+			}
+		} else {
+			t.Status = StatusCompleted
+			t.Progress = "100%"
+			t.Percent = 100
+		}
+		t.cmd = nil
+		t.cancel = nil
+		t.UpdatedAt = time.Now()
+		t.mu.Unlock()
+
+		events <- Event{Done: true, Err: waitErr}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,136 @@
+package download
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bandwidthWindow is one EWMA smoothing constant, named for roughly how long
+// it takes a step change in throughput to mostly settle. yt-dlp's own
+// --newline speed figure is a noisy instantaneous reading; these smooth it
+// at three time scales so the UI can tell "just blipped" from "stalling".
+type bandwidthWindow struct {
+	halfLife time.Duration
+	rate     float64 // smoothed bytes/sec
+}
+
+func (w *bandwidthWindow) update(instant float64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	alpha := 1 - math.Exp(-elapsed.Seconds()/w.halfLife.Seconds())
+	w.rate += alpha * (instant - w.rate)
+}
+
+func newTaskWindows() *taskWindows {
+	return &taskWindows{
+		w1s:  bandwidthWindow{halfLife: time.Second},
+		w10s: bandwidthWindow{halfLife: 10 * time.Second},
+		w60s: bandwidthWindow{halfLife: 60 * time.Second},
+	}
+}
+
+type taskWindows struct {
+	w1s, w10s, w60s bandwidthWindow
+}
+
+func (tw *taskWindows) fold(instant float64, elapsed time.Duration) {
+	tw.w1s.update(instant, elapsed)
+	tw.w10s.update(instant, elapsed)
+	tw.w60s.update(instant, elapsed)
+}
+
+// TaskBandwidth is one task's (or the manager-wide aggregate's) smoothed
+// throughput at three time scales, in bytes/sec.
+type TaskBandwidth struct {
+	TaskID  string  `json:"task_id,omitempty"`
+	Rate1s  float64 `json:"rate_1s"`
+	Rate10s float64 `json:"rate_10s"`
+	Rate60s float64 `json:"rate_60s"`
+}
+
+// Bandwidth is the manager-wide bandwidth snapshot served by GET
+// /api/bandwidth and pushed over the WebSocket hub once a second.
+type Bandwidth struct {
+	Aggregate TaskBandwidth   `json:"aggregate"`
+	Tasks     []TaskBandwidth `json:"tasks"`
+}
+
+// bandwidthMeter derives rolling throughput from each task's cumulative
+// BytesTotal counter, sampled once a second — it never touches the reader
+// path directly, so it works the same whether bytes came from yt-dlp's
+// percent-of-total progress lines or a backend's own byte counter.
+type bandwidthMeter struct {
+	mu        sync.Mutex
+	lastTime  time.Time
+	lastBytes map[string]int64
+	windows   map[string]*taskWindows
+	aggregate *taskWindows
+}
+
+func newBandwidthMeter() *bandwidthMeter {
+	return &bandwidthMeter{
+		lastTime:  time.Now(),
+		lastBytes: make(map[string]int64),
+		windows:   make(map[string]*taskWindows),
+		aggregate: newTaskWindows(),
+	}
+}
+
+// sample computes each task's instantaneous rate since the previous call
+// and folds it into that task's (and the aggregate's) EWMA windows. Tasks
+// no longer present (completed and GC'd, or deleted) drop out of the
+// tracked set so it doesn't grow unbounded over a long-lived instance.
+func (bm *bandwidthMeter) sample(tasks []*Task) Bandwidth {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(bm.lastTime)
+	bm.lastTime = now
+
+	seen := make(map[string]bool, len(tasks))
+	var aggInstant float64
+	out := Bandwidth{Tasks: make([]TaskBandwidth, 0, len(tasks))}
+
+	for _, t := range tasks {
+		total := t.BytesTotal()
+		prev, had := bm.lastBytes[t.ID]
+		bm.lastBytes[t.ID] = total
+		seen[t.ID] = true
+		if !had || elapsed <= 0 {
+			continue
+		}
+
+		instant := float64(total-prev) / elapsed.Seconds()
+		if instant < 0 {
+			instant = 0 // a retry/backend switch can reset BytesTotal lower
+		}
+		aggInstant += instant
+
+		tw := bm.windows[t.ID]
+		if tw == nil {
+			tw = newTaskWindows()
+			bm.windows[t.ID] = tw
+		}
+		tw.fold(instant, elapsed)
+
+		out.Tasks = append(out.Tasks, TaskBandwidth{
+			TaskID: t.ID, Rate1s: tw.w1s.rate, Rate10s: tw.w10s.rate, Rate60s: tw.w60s.rate,
+		})
+	}
+
+	for id := range bm.lastBytes {
+		if !seen[id] {
+			delete(bm.lastBytes, id)
+			delete(bm.windows, id)
+		}
+	}
+
+	bm.aggregate.fold(aggInstant, elapsed)
+	out.Aggregate = TaskBandwidth{
+		Rate1s: bm.aggregate.w1s.rate, Rate10s: bm.aggregate.w10s.rate, Rate60s: bm.aggregate.w60s.rate,
+	}
+	return out
+}
@@ -1,64 +1,254 @@
 package download
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"yt-dlp-web/internal/config"
+	"yt-dlp-web/internal/logging"
+	"yt-dlp-web/internal/metrics"
+
+	"github.com/getsentry/sentry-go"
 )
 
 // Broadcaster is a callback to push task updates to connected WS clients.
 type Broadcaster func(t *Task)
 
+// BandwidthBroadcaster is a callback to push the once-a-second manager-wide
+// bandwidth snapshot to connected WS clients, mirroring Broadcaster.
+type BandwidthBroadcaster func(b Bandwidth)
+
 // Manager owns the task map, worker pool, and yt-dlp execution.
+//
+// It's a two-layer design, similar to Docker's transfer manager: `transfers`
+// deduplicates concurrent submissions of the same (URL, format, output
+// template) so they share one yt-dlp process, while the priority queues on
+// top decide which queued task a worker picks up next.
 type Manager struct {
-	tasks    map[string]*Task
-	order    []string // insertion order for stable listing
-	mu       sync.RWMutex
-	queue    chan string // task IDs
+	tasks     map[string]*Task
+	order     []string // insertion order for stable listing
+	mu        sync.RWMutex
+	transfers *transferRegistry
+	backends  []Backend // tried in order for "auto" selection; see selectBackend
+	store     Store     // nil disables persistence (e.g. store open failed)
+
+	// Three priority tiers, drained high > normal > low by each worker.
+	queueHigh   chan string
+	queueNormal chan string
+	queueLow    chan string
+
+	// Bandwidth accounting/limiting. globalLimiter is shared by every task
+	// (cfg.MaxBytesPerSec); bw samples each task's byte counter once a
+	// second into rolling rates; bwMu/bwLast cache the latest snapshot for
+	// GET /api/bandwidth so it doesn't have to wait on the next tick.
+	globalLimiter *tokenBucket
+	bw            *bandwidthMeter
+	bwBc          BandwidthBroadcaster
+	bwMu          sync.RWMutex
+	bwLast        Bandwidth
+
 	cfg      *config.Config
 	bc       Broadcaster
+	metrics  *metrics.Metrics // nil disables Prometheus instrumentation
+	log      *slog.Logger
 	done     chan struct{} // closed on Shutdown
 	shutdown sync.Once
+
+	// ytdlpVerOnce/ytdlpVer cache `yt-dlp --version`'s output (run at most
+	// once per process) purely for tagging Sentry events — not worth a
+	// config field since it's only ever read there.
+	ytdlpVerOnce sync.Once
+	ytdlpVer     string
 }
 
-// NewManager creates the manager and starts worker goroutines.
-func NewManager(cfg *config.Config, bc Broadcaster) *Manager {
+// buildBackends assembles the ordered backend list "auto" selection walks:
+// direct-file URLs prefer the lighter HTTP/aria2 paths over starting yt-dlp,
+// and yt-dlp is always last since Supports() is true for everything.
+func buildBackends(cfg *config.Config) []Backend {
+	backends := make([]Backend, 0, 3)
+	if cfg.HTTPBackendEnabled {
+		backends = append(backends, HTTPBackend{})
+	}
+	if cfg.Aria2Enabled {
+		backends = append(backends, Aria2Backend{RPCURL: cfg.Aria2RPCURL, Secret: cfg.Aria2Secret})
+	}
+	backends = append(backends, YtDlpBackend{})
+	return backends
+}
+
+// NewManager creates the manager, recovers any tasks persisted by a
+// previous run, and starts worker goroutines.
+func NewManager(cfg *config.Config, bc Broadcaster, bwBc BandwidthBroadcaster, mtr *metrics.Metrics) *Manager {
+	mlog := logging.Named("download")
+
+	var store Store
+	if bs, err := NewBoltStore(cfg.TaskStorePath); err != nil {
+		mlog.Warn("task store unavailable, falling back to in-memory only", "error", err)
+	} else {
+		store = bs
+	}
+
 	m := &Manager{
-		tasks: make(map[string]*Task),
-		order: make([]string, 0),
-		queue: make(chan string, 512),
-		cfg:   cfg,
-		bc:    bc,
-		done:  make(chan struct{}),
+		tasks:         make(map[string]*Task),
+		order:         make([]string, 0),
+		transfers:     newTransferRegistry(),
+		backends:      buildBackends(cfg),
+		store:         store,
+		queueHigh:     make(chan string, 512),
+		queueNormal:   make(chan string, 512),
+		queueLow:      make(chan string, 512),
+		globalLimiter: newTokenBucket(float64(cfg.MaxBytesPerSec)),
+		bw:            newBandwidthMeter(),
+		bwBc:          bwBc,
+		cfg:           cfg,
+		bc:            bc,
+		metrics:       mtr,
+		log:           mlog,
+		done:          make(chan struct{}),
+	}
+
+	if store != nil {
+		m.recover()
+		if cfg.RetainCompleted > 0 {
+			go m.gcLoop()
+		}
 	}
+
 	for i := 0; i < cfg.MaxConcurrent; i++ {
 		go m.worker()
 	}
+	go m.bandwidthLoop()
 	return m
 }
 
-// Submit adds a new task to the queue.
+// recover loads every task the store has and rebuilds the in-memory map: a
+// task still StatusRunning when the process last stopped never got to
+// transition on its own (the process just died), so it's marked Paused —
+// and re-queued immediately if cfg.AutoResume is set. yt-dlp's own
+// --continue plus the .part/.ytdl sidecars it leaves behind let a requeued
+// task pick up roughly where it left off instead of restarting from zero.
+func (m *Manager) recover() {
+	tasks, err := m.store.Load()
+	if err != nil {
+		m.log.Error("failed to load persisted tasks", "error", err)
+		return
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+
+	m.mu.Lock()
+	for _, t := range tasks {
+		if t.Status == StatusRunning {
+			t.Status = StatusPaused
+			t.UpdatedAt = time.Now()
+		}
+		m.tasks[t.ID] = t
+		m.order = append(m.order, t.ID)
+	}
+	m.mu.Unlock()
+
+	for _, t := range tasks {
+		if m.cfg.AutoResume && (t.Status == StatusPaused || t.Status == StatusQueued) {
+			t.mu.Lock()
+			t.Status = StatusQueued
+			t.mu.Unlock()
+			m.sendQueue(t.ID, t.Priority)
+		}
+	}
+	m.log.Info("recovered persisted tasks", "count", len(tasks))
+}
+
+// gcLoop periodically removes completed/failed/cancelled tasks older than
+// cfg.RetainCompleted, so a long-lived instance doesn't accumulate an
+// unbounded task history in both memory and the store.
+func (m *Manager) gcLoop() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.gcOnce()
+		}
+	}
+}
+
+func (m *Manager) gcOnce() {
+	cutoff := time.Now().Add(-m.cfg.RetainCompleted)
+	var toDelete []string
+	m.mu.RLock()
+	for id, t := range m.tasks {
+		t.mu.Lock()
+		expired := (t.Status == StatusCompleted || t.Status == StatusFailed || t.Status == StatusCancelled) && t.UpdatedAt.Before(cutoff)
+		t.mu.Unlock()
+		if expired {
+			toDelete = append(toDelete, id)
+		}
+	}
+	m.mu.RUnlock()
+	for _, id := range toDelete {
+		_ = m.Delete(id)
+	}
+	if len(toDelete) > 0 {
+		m.log.Info("gc: removed expired tasks", "count", len(toDelete), "retain_completed", m.cfg.RetainCompleted)
+	}
+}
+
+// SubmitOptions carries the per-submission knobs Submit understands;
+// currently just Priority (MaxRetries/RetryBackoff live on the Task itself,
+// set via NewTaskWithOptions before calling Submit).
+type SubmitOptions struct {
+	Priority Priority
+}
+
+// Submit adds a new task to its priority queue.
 func (m *Manager) Submit(t *Task) {
+	m.SubmitWithOptions(t, SubmitOptions{Priority: t.Priority})
+}
+
+// SubmitWithOptions adds a new task to the queue matching opts.Priority,
+// overriding the priority already set on t (if any).
+func (m *Manager) SubmitWithOptions(t *Task, opts SubmitOptions) {
+	if opts.Priority != "" {
+		t.Priority = opts.Priority
+	}
 	m.mu.Lock()
 	m.tasks[t.ID] = t
 	m.order = append(m.order, t.ID)
 	m.mu.Unlock()
 	m.broadcast(t)
-	if !m.sendQueue(t.ID) {
+	if !m.sendQueue(t.ID, t.Priority) {
 		m.failTask(t, fmt.Errorf("queue full or shutting down, try again later"))
 	}
 }
 
+// SetPriority changes a queued/running task's priority. For a task still
+// waiting in a queue, the new priority only takes effect the next time it is
+// (re)enqueued — draining channels mid-flight isn't worth the complexity
+// given a full requeue is immediate anyway.
+func (m *Manager) SetPriority(id string, p Priority) error {
+	t, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("not found")
+	}
+	t.mu.Lock()
+	t.Priority = p
+	t.UpdatedAt = time.Now()
+	t.mu.Unlock()
+	m.broadcast(t)
+	return nil
+}
+
 // List returns all tasks sorted newest-first.
 func (m *Manager) List() []map[string]interface{} {
 	m.mu.RLock()
@@ -87,6 +277,8 @@ func (m *Manager) Cancel(id string) error {
 		return fmt.Errorf("not found")
 	}
 	t.mu.Lock()
+	key := t.transferKey
+	isSubscriberOnly := t.cancel == nil
 	switch t.Status {
 	case StatusRunning:
 		if t.cancel != nil {
@@ -104,6 +296,13 @@ func (m *Manager) Cancel(id string) error {
 	}
 	t.UpdatedAt = time.Now()
 	t.mu.Unlock()
+
+	// A subscriber piggybacking on someone else's Transfer has no process of
+	// its own to kill — only drop the shared process once it's the last one watching.
+	if isSubscriberOnly && key != "" {
+		m.transfers.cancelSubscriber(key, t.ID)
+	}
+
 	m.broadcast(t)
 	return nil
 }
@@ -145,7 +344,7 @@ func (m *Manager) Resume(id string) error {
 	t.UpdatedAt = time.Now()
 	t.mu.Unlock()
 	m.broadcast(t)
-	if !m.sendQueue(t.ID) {
+	if !m.sendQueue(t.ID, t.Priority) {
 		m.failTask(t, fmt.Errorf("queue full or shutting down, try again later"))
 	}
 	return nil
@@ -169,10 +368,11 @@ func (m *Manager) Retry(id string) error {
 	t.ETA = ""
 	t.Error = ""
 	t.Logs = t.Logs[:0]
+	t.RetryCount = 0
 	t.UpdatedAt = time.Now()
 	t.mu.Unlock()
 	m.broadcast(t)
-	if !m.sendQueue(t.ID) {
+	if !m.sendQueue(t.ID, t.Priority) {
 		m.failTask(t, fmt.Errorf("queue full or shutting down, try again later"))
 	}
 	return nil
@@ -197,6 +397,16 @@ func (m *Manager) Delete(id string) error {
 	m.order = newOrder
 	m.mu.Unlock()
 
+	if m.store != nil {
+		if err := m.store.Delete(id); err != nil {
+			m.log.Warn("failed to delete persisted task", "task_id", id, "request_id", t.RequestID, "error", err)
+		}
+	}
+	t.mu.Lock()
+	if t.logWriter != nil {
+		t.logWriter.Close()
+	}
+	t.mu.Unlock()
 	m.removeTaskFiles(t.Filename)
 	return nil
 }
@@ -227,6 +437,11 @@ func (m *Manager) ClearCompleted() int {
 
 	// Best-effort physical file deletion for cleared tasks
 	for _, t := range toDelete {
+		if m.store != nil {
+			if err := m.store.Delete(t.ID); err != nil {
+				m.log.Warn("failed to delete persisted task", "task_id", t.ID, "request_id", t.RequestID, "error", err)
+			}
+		}
 		m.removeTaskFiles(t.Filename)
 	}
 
@@ -295,19 +510,32 @@ func (m *Manager) removeTaskFiles(filename string) {
 	}
 }
 
-// sendQueue safely sends a task ID to the queue, returning false if shutdown.
-func (m *Manager) sendQueue(id string) bool {
+// sendQueue safely sends a task ID to the queue matching priority, returning
+// false if shutdown or the queue is full.
+func (m *Manager) sendQueue(id string, priority Priority) bool {
+	q := m.queueFor(priority)
 	select {
 	case <-m.done:
 		return false
-	case m.queue <- id:
+	case q <- id:
 		return true
 	default:
 		return false
 	}
 }
 
-// Shutdown cancels all running tasks and closes the queue.
+func (m *Manager) queueFor(priority Priority) chan string {
+	switch priority {
+	case PriorityHigh:
+		return m.queueHigh
+	case PriorityLow:
+		return m.queueLow
+	default:
+		return m.queueNormal
+	}
+}
+
+// Shutdown cancels all running tasks and closes the queues.
 func (m *Manager) Shutdown() {
 	m.shutdown.Do(func() {
 		close(m.done)
@@ -320,7 +548,14 @@ func (m *Manager) Shutdown() {
 			t.mu.Unlock()
 		}
 		m.mu.RUnlock()
-		close(m.queue)
+		close(m.queueHigh)
+		close(m.queueNormal)
+		close(m.queueLow)
+		if m.store != nil {
+			if err := m.store.Close(); err != nil {
+				m.log.Error("failed to close task store", "error", err)
+			}
+		}
 	})
 }
 
@@ -328,10 +563,22 @@ func (m *Manager) broadcast(t *Task) {
 	if m.bc != nil {
 		m.bc(t)
 	}
+	if m.store != nil {
+		if err := m.store.Save(t); err != nil {
+			m.log.Warn("failed to persist task", "task_id", t.ID, "request_id", t.RequestID, "error", err)
+		}
+	}
 }
 
+// worker drains the high-priority queue first, then normal, then low —
+// falling through to a blocking select across all three once every tier is
+// momentarily empty, so workers idle instead of busy-polling.
 func (m *Manager) worker() {
-	for id := range m.queue {
+	for {
+		id, ok := m.nextTaskID()
+		if !ok {
+			return
+		}
 		t, ok := m.Get(id)
 		if !ok {
 			continue
@@ -346,153 +593,486 @@ func (m *Manager) worker() {
 	}
 }
 
+// nextTaskID pops the next task ID in priority order, blocking until one is
+// available or the manager shuts down (ok=false).
+func (m *Manager) nextTaskID() (id string, ok bool) {
+	select {
+	case id, ok = <-m.queueHigh:
+		return id, ok
+	default:
+	}
+	select {
+	case id, ok = <-m.queueHigh:
+		return id, ok
+	case id, ok = <-m.queueNormal:
+		return id, ok
+	default:
+	}
+	select {
+	case id, ok = <-m.queueHigh:
+		return id, ok
+	case id, ok = <-m.queueNormal:
+		return id, ok
+	case id, ok = <-m.queueLow:
+		return id, ok
+	}
+}
+
+// execute runs (or joins) the Transfer backing t, then dispatches to
+// runTransfer for the subscriber that actually owns the process.
 func (m *Manager) execute(t *Task) {
-	ctx, cancel := context.WithCancel(context.Background())
+	key := transferKey(t.URL, t.Args)
 	t.mu.Lock()
-	t.Status = StatusRunning
-	t.cancel = cancel
-	t.UpdatedAt = time.Now()
+	t.transferKey = key
 	t.mu.Unlock()
-	defer cancel()
-	m.broadcast(t)
 
-	// Build args: defaults + user args + URL
-	// If user specified -o, skip the default -o from DefaultArgs
-	hasUserOutput := false
-	for _, a := range t.Args {
-		if a == "-o" || a == "--output" {
-			hasUserOutput = true
-			break
-		}
+	tr, shouldStart := m.transfers.joinOrCreate(key, t)
+	if !shouldStart {
+		m.waitForTransfer(tr, t)
+		return
 	}
-	args := make([]string, 0, len(m.cfg.DefaultArgs)+len(t.Args)+1)
-	for i := 0; i < len(m.cfg.DefaultArgs); i++ {
-		if m.cfg.DefaultArgs[i] == "-o" && hasUserOutput && i+1 < len(m.cfg.DefaultArgs) {
-			i++ // skip -o and its value
-			continue
+	defer m.transfers.release(key, t.ID)
+
+	m.openTaskLog(t)
+	m.wireLimiter(t)
+	m.runTransfer(tr, t)
+
+	// If the primary retries, every other task that was dedup'd onto the
+	// same Transfer retries too — they shared the one process this attempt,
+	// so leaving them mirrored as permanently Failed while the primary's
+	// retry goes on to succeed would contradict the whole point of
+	// deduplication. Subscribers don't accumulate their own Logs (only the
+	// primary's backend writes those), so the decision is the primary's
+	// alone; subscribers just follow it.
+	if t.shouldRetry() {
+		m.scheduleRetry(t)
+		for _, sub := range tr.retrySubs {
+			m.scheduleRetry(sub)
 		}
-		args = append(args, m.cfg.DefaultArgs[i])
 	}
-	args = append(args, t.Args...)
-	args = append(args, t.URL)
+}
 
-	// Check yt-dlp exists: stat first, then PATH lookup
-	if strings.HasPrefix(m.cfg.YtDlpPath, "NOT_FOUND|") {
-		m.failTask(t, fmt.Errorf("YT-DLP NOT FOUND!\n\n%s", m.cfg.YtDlpPath))
+// openTaskLog lazily opens t's per-task log file under
+// ConfigDir/logs/<id>.log so AddLog mirrors there in addition to the
+// in-memory ring buffer; it's a no-op once already open (e.g. a retry).
+func (m *Manager) openTaskLog(t *Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logWriter != nil {
+		return
+	}
+	w, err := newTaskLogWriter(filepath.Join(m.cfg.ConfigDir, "logs"), t.ID)
+	if err != nil {
+		m.log.Warn("failed to open per-task log file", "task_id", t.ID, "request_id", t.RequestID, "error", err)
 		return
 	}
-	if _, err := os.Stat(m.cfg.YtDlpPath); os.IsNotExist(err) {
-		if _, lookErr := exec.LookPath(m.cfg.YtDlpPath); lookErr != nil {
-			m.failTask(t, fmt.Errorf("yt-dlp not found at %s or in PATH", m.cfg.YtDlpPath))
+	t.logWriter = w
+}
+
+// wireLimiter attaches the manager's shared global rate limiter and ensures
+// t has its own per-task limiter, so HTTPBackend's read loop — the only
+// backend that can honor a byte budget mid-flight rather than just at
+// process start — can throttle against both without needing a Manager
+// reference of its own.
+func (m *Manager) wireLimiter(t *Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.globalLimiter = m.globalLimiter
+	if t.limiter == nil {
+		t.limiter = newTokenBucket(float64(t.RateLimit))
+	}
+}
+
+// SetGlobalLimit changes the manager-wide bandwidth cap at runtime (0
+// disables it). Takes effect immediately for HTTPBackend transfers in
+// flight; yt-dlp/aria2 transfers already running keep the cap they started
+// with, same as SetTaskLimit.
+func (m *Manager) SetGlobalLimit(bytesPerSec int64) {
+	m.cfg.MaxBytesPerSec = bytesPerSec
+	m.globalLimiter.SetRate(float64(bytesPerSec))
+}
+
+// SetTaskLimit changes a single task's own bandwidth cap at runtime (0
+// disables it). Takes effect immediately for HTTPBackend transfers in
+// flight; a yt-dlp/aria2 transfer already running only picks it up on its
+// next retry, since those read the cap once at process start.
+func (m *Manager) SetTaskLimit(id string, bytesPerSec int64) error {
+	t, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.mu.Lock()
+	t.RateLimit = bytesPerSec
+	if t.limiter == nil {
+		t.limiter = newTokenBucket(float64(bytesPerSec))
+	} else {
+		t.limiter.SetRate(float64(bytesPerSec))
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// bandwidthLoop samples every task's byte counter once a second, folds it
+// into bw's rolling rates, caches the result for GET /api/bandwidth, and
+// pushes it to the WS hub the same way broadcast() pushes task updates.
+func (m *Manager) bandwidthLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
 			return
+		case <-ticker.C:
+			m.mu.RLock()
+			tasks := make([]*Task, 0, len(m.tasks))
+			for _, t := range m.tasks {
+				tasks = append(tasks, t)
+			}
+			m.mu.RUnlock()
+
+			snap := m.bw.sample(tasks)
+			m.bwMu.Lock()
+			m.bwLast = snap
+			m.bwMu.Unlock()
+			if m.bwBc != nil {
+				m.bwBc(snap)
+			}
 		}
 	}
+}
+
+// Bandwidth returns the most recently sampled manager-wide bandwidth
+// snapshot, for GET /api/bandwidth.
+func (m *Manager) Bandwidth() Bandwidth {
+	m.bwMu.RLock()
+	defer m.bwMu.RUnlock()
+	return m.bwLast
+}
+
+// ShuttingDown reports whether Shutdown has been called, for GET /readyz
+// (and /livez) to flip to 503 during graceful drain before the listener
+// actually closes.
+func (m *Manager) ShuttingDown() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
 
-	// Log yt-dlp path for debugging
-	log.Printf("[execute] YtDlpPath=%s, UsePython=%v", m.cfg.YtDlpPath, m.cfg.UsePython)
-	log.Printf("[execute] args=%v", args)
+// QueueDepth and QueueCapacity report how full the three priority queues
+// are combined, for GET /readyz, which fails once the combined depth reaches
+// the combined capacity.
+func (m *Manager) QueueDepth() int {
+	return len(m.queueHigh) + len(m.queueNormal) + len(m.queueLow)
+}
 
-	// Ensure download & cache dirs exist before every execution.
-	// On Android the dirs may vanish after startup (storage cleanup, permission changes).
-	os.MkdirAll(m.cfg.DownloadDir, 0o755)
-	os.MkdirAll(filepath.Join(m.cfg.ConfigDir, "cache"), 0o755)
+func (m *Manager) QueueCapacity() int {
+	return cap(m.queueHigh) + cap(m.queueNormal) + cap(m.queueLow)
+}
 
-	var cmd *exec.Cmd
-	if m.cfg.UsePython {
-		// Python mode: find python3 in the same directory as the script
-		scriptDir := filepath.Dir(m.cfg.YtDlpPath)
-		pythonPath := filepath.Join(scriptDir, "python3")
-		if _, err := os.Stat(pythonPath); os.IsNotExist(err) {
-			// Try parent directories
-			pythonPath = filepath.Join(scriptDir, "..", "python3")
-		}
-		log.Printf("[execute] Using Python: %s %s", pythonPath, m.cfg.YtDlpPath)
-		cmd = exec.CommandContext(ctx, pythonPath, append([]string{m.cfg.YtDlpPath}, args...)...)
-	} else {
-		cmd = exec.CommandContext(ctx, m.cfg.YtDlpPath, args...)
+// FilePath returns the on-disk path of t's output file, for GET
+// /api/download/:id/file. Errors if the task doesn't exist or hasn't
+// produced a file yet (Task.Filename is set from yt-dlp's "Destination:"
+// line or a backend's own output path once it's known).
+func (m *Manager) FilePath(id string) (string, error) {
+	t, ok := m.Get(id)
+	if !ok {
+		return "", fmt.Errorf("task %s not found", id)
 	}
-	cmd.Dir = m.cfg.DownloadDir // yt-dlp resolves relative -o paths from cwd
-	cmd.Env = append(os.Environ(),
-		"XDG_CACHE_HOME="+m.cfg.ConfigDir+"/cache",
-		"XDG_CONFIG_HOME="+m.cfg.ConfigDir,
-		"HOME="+m.cfg.ConfigDir,
-	)
+	t.mu.Lock()
+	path := t.Filename
+	t.mu.Unlock()
+	if path == "" {
+		return "", fmt.Errorf("task %s has no output file yet", id)
+	}
+	return path, nil
+}
 
-	// Capture stdout and stderr separately, merged into one scanner
-	stdoutPipe, err := cmd.StdoutPipe()
+// ReadTaskLog reads t's persisted log file starting at byte offset 0-aligned
+// to a previous call's returned nextOffset, for GET /api/tasks/:id/logs
+// pagination and tail-follow polling. If the file doesn't exist yet (task
+// never started, or its log file failed to open), it falls back to the
+// in-memory ring buffer so callers still get something.
+func (m *Manager) ReadTaskLog(id string, offset int64) (lines []string, nextOffset int64, err error) {
+	t, ok := m.Get(id)
+	if !ok {
+		return nil, offset, fmt.Errorf("not found")
+	}
+	path := filepath.Join(m.cfg.ConfigDir, "logs", id+".log")
+	f, err := os.Open(path)
 	if err != nil {
-		m.failTask(t, err)
-		return
+		if os.IsNotExist(err) {
+			t.mu.Lock()
+			lines = append([]string(nil), t.Logs...)
+			t.mu.Unlock()
+			return lines, offset, nil
+		}
+		return nil, offset, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
 	}
-	stderrPipe, err := cmd.StderrPipe()
+	data, err := io.ReadAll(f)
 	if err != nil {
-		m.failTask(t, err)
-		return
+		return nil, offset, err
+	}
+	nextOffset = offset + int64(len(data))
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nextOffset, nil
+	}
+	return strings.Split(text, "\n"), nextOffset, nil
+}
+
+// waitForTransfer blocks until tr's process finishes, then mirrors its
+// terminal state onto t (a subscriber piggybacking on tr) and broadcasts.
+func (m *Manager) waitForTransfer(tr *Transfer, t *Task) {
+	<-tr.done
+	tr.mu.Lock()
+	primary := tr.primary
+	tr.mu.Unlock()
+	if primary != nil {
+		m.mirrorTask(primary, t)
+	}
+	m.broadcast(t)
+	m.transfers.release(tr.key, t.ID)
+}
+
+// mirrorTask copies src's download-progress fields onto dst, used to fan out
+// one Transfer's state to every Task subscribed to it.
+func (m *Manager) mirrorTask(src, dst *Task) {
+	src.mu.Lock()
+	status, progress, percent := src.Status, src.Progress, src.Percent
+	size, speed, eta := src.Size, src.Speed, src.ETA
+	filename, errStr := src.Filename, src.Error
+	src.mu.Unlock()
+
+	dst.mu.Lock()
+	dst.Status, dst.Progress, dst.Percent = status, progress, percent
+	dst.Size, dst.Speed, dst.ETA = size, speed, eta
+	dst.Filename, dst.Error = filename, errStr
+	dst.UpdatedAt = time.Now()
+	dst.mu.Unlock()
+}
+
+// broadcastToSubscribers mirrors primary's current state onto every other
+// task sharing its Transfer and broadcasts each, so two tasks submitted for
+// the same URL+format+output see the same live progress.
+func (m *Manager) broadcastToSubscribers(tr *Transfer, primary *Task) {
+	for _, sub := range tr.snapshotSubscribers() {
+		if sub.ID == primary.ID {
+			continue
+		}
+		m.mirrorTask(primary, sub)
+		m.broadcast(sub)
+	}
+}
+
+// metricSite extracts a label-friendly site identifier from a task URL
+// (the host, minus a leading "www."), so /metrics can break download
+// volume down by extractor/site without a label value per distinct URL.
+func metricSite(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
 	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// metricFormat reports the coarse "audio"/"video" label /metrics uses —
+// not yt-dlp's full format selector, just enough to split the two.
+func metricFormat(args []string) string {
+	for _, a := range args {
+		if a == "-x" || a == "--extract-audio" {
+			return "audio"
+		}
+	}
+	return "video"
+}
+
+// ytdlpVersion runs `yt-dlp --version` once and caches the result, for
+// tagging Sentry events — a best-effort "unknown" on any failure rather
+// than holding up a download over it.
+func (m *Manager) ytdlpVersion() string {
+	m.ytdlpVerOnce.Do(func() {
+		out, err := exec.Command(m.cfg.YtDlpPath, "--version").Output()
+		if err != nil {
+			m.ytdlpVer = "unknown"
+			return
+		}
+		m.ytdlpVer = strings.TrimSpace(string(out))
+	})
+	return m.ytdlpVer
+}
 
-	if err := cmd.Start(); err != nil {
-		// If YtDlpPath is a diagnostic string, show it instead of system error
-		if strings.HasPrefix(m.cfg.YtDlpPath, "NOT_FOUND|") {
-			m.failTask(t, fmt.Errorf("YT-DLP NOT FOUND!\n\nDiagnostic info:\n%s", m.cfg.YtDlpPath))
-		} else {
-			m.failTask(t, err)
+// runTransfer resolves t's Backend (yt-dlp by default) and drains its Event
+// stream, which is t == tr.primary for the lifetime of the process/job.
+func (m *Manager) runTransfer(tr *Transfer, t *Task) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tr.mu.Lock()
+	tr.primary = t
+	tr.cancel = cancel
+	tr.mu.Unlock()
+	defer func() {
+		// Snapshot every other subscriber before done closes, so execute can
+		// fan a primary retry out to them too — once done is closed, each
+		// subscriber's own waitForTransfer goroutine unsubscribes it, and
+		// this is the last moment they're still reachable through tr.
+		tr.mu.Lock()
+		subs := make([]*Task, 0, len(tr.subscribers))
+		for id, sub := range tr.subscribers {
+			if id != t.ID {
+				subs = append(subs, sub)
+			}
 		}
+		tr.retrySubs = subs
+		tr.mu.Unlock()
+		cancel()
+		close(tr.done)
+	}()
+
+	backend := selectBackend(m.backends, t.Backend, t.URL)
+	if backend == nil {
+		cancel()
+		// Never actually started, so it doesn't count against
+		// DownloadsStarted/ActiveTasks — there's nothing to stop.
+		m.failTask(t, fmt.Errorf("no backend available for %q (requested %q)", t.URL, t.Backend))
 		return
 	}
 
 	t.mu.Lock()
-	t.cmd = cmd
+	t.Backend = backend.Name()
+	t.cancel = cancel
+	t.Status = StatusRunning
+	t.UpdatedAt = time.Now()
 	t.mu.Unlock()
 
-	// Merge stdout + stderr
-	// Read stdout and stderr concurrently into a combined channel
-	lines := make(chan string, 64)
-	var wg sync.WaitGroup
-	readPipe := func(r io.Reader) {
-		defer wg.Done()
-		sc := bufio.NewScanner(r)
-		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-		for sc.Scan() {
-			lines <- sc.Text()
-		}
-	}
-	wg.Add(2)
-	go readPipe(stdoutPipe)
-	go readPipe(stderrPipe)
-	go func() { wg.Wait(); close(lines) }()
-
-	for line := range lines {
-		t.AddLog(line) // AddLog has its own lock
-		t.mu.Lock()
-		changed := ParseLine(line, t)
-		t.mu.Unlock()
-		if changed {
-			m.broadcast(t)
-		}
+	site, format := metricSite(t.URL), metricFormat(t.Args)
+	if m.metrics != nil {
+		m.metrics.DownloadsStarted.WithLabelValues(site, format).Inc()
+		m.metrics.ActiveTasks.Inc()
 	}
+	m.log.Info("task started",
+		"task_id", t.ID, "request_id", t.RequestID, "url", t.URL,
+		"site", site, "format", format, "backend", backend.Name())
+	sentry.AddBreadcrumb(&sentry.Breadcrumb{
+		Category: "task",
+		Message:  "task started",
+		Level:    sentry.LevelInfo,
+		Data:     map[string]interface{}{"task_id": t.ID, "site": site, "format": format, "backend": backend.Name()},
+	})
+	started := time.Now()
+	var lastBytes int64
 
-	waitErr := cmd.Wait()
+	events, err := backend.Start(ctx, t, m.cfg)
+	if err != nil {
+		if m.metrics != nil {
+			m.metrics.ActiveTasks.Dec()
+			m.metrics.DownloadsFailed.WithLabelValues(site, format).Inc()
+			m.metrics.DownloadsCompleted.WithLabelValues(site, format, string(StatusFailed)).Inc()
+		}
+		m.reportToSentry(t, site, backend.Name(), err)
+		m.failTask(t, err)
+		return
+	}
+	m.broadcast(t)
 
-	t.mu.Lock()
-	if waitErr != nil {
-		if ctx.Err() == context.Canceled {
-			if t.Status != StatusPaused {
-				t.Status = StatusCancelled
+	for ev := range events {
+		if ev.Changed {
+			if m.metrics != nil {
+				if b := t.BytesTotal(); b > lastBytes {
+					m.metrics.BytesDownloaded.WithLabelValues(site, format).Add(float64(b - lastBytes))
+					lastBytes = b
+				}
 			}
-		} else {
-			t.Status = StatusFailed
-			t.Error = waitErr.Error()
+			m.broadcast(t)
+			m.broadcastToSubscribers(tr, t)
+		}
+		if ev.Done {
+			t.mu.Lock()
+			t.cmd = nil
+			t.cancel = nil
+			status := t.Status
+			t.mu.Unlock()
+			if m.metrics != nil {
+				m.metrics.ActiveTasks.Dec()
+				m.metrics.DownloadDuration.WithLabelValues(site, format).Observe(time.Since(started).Seconds())
+				m.metrics.DownloadsCompleted.WithLabelValues(site, format, string(status)).Inc()
+				if status == StatusFailed {
+					m.metrics.DownloadsFailed.WithLabelValues(site, format).Inc()
+				}
+			}
+			m.log.Info("task finished",
+				"task_id", t.ID, "request_id", t.RequestID, "url", t.URL, "site", site,
+				"status", string(status), "bytes", t.BytesTotal(), "duration_ms", time.Since(started).Milliseconds())
+			sentry.AddBreadcrumb(&sentry.Breadcrumb{
+				Category: "task",
+				Message:  "task finished",
+				Level:    sentry.LevelInfo,
+				Data:     map[string]interface{}{"task_id": t.ID, "site": site, "status": string(status), "bytes": t.BytesTotal()},
+			})
+			if status == StatusFailed && ev.Err != nil {
+				m.reportToSentry(t, site, backend.Name(), ev.Err)
+			}
+			m.broadcast(t)
+			m.broadcastToSubscribers(tr, t)
 		}
-	} else {
-		t.Status = StatusCompleted
-		t.Progress = "100%"
-		t.Percent = 100
 	}
-	t.cmd = nil
-	t.cancel = nil
-	t.UpdatedAt = time.Now()
+}
+
+// scheduleRetry waits out t's exponential backoff, then re-queues it. The
+// delay runs in its own goroutine so the worker that just finished t is
+// immediately free to pick up the next queued task.
+func (m *Manager) scheduleRetry(t *Task) {
+	t.mu.Lock()
+	t.RetryCount++
+	attempt := t.RetryCount
+	backoff := t.RetryBackoff
+	priority := t.Priority
 	t.mu.Unlock()
-	m.broadcast(t)
+
+	delay := backoffDelay(backoff, 60*time.Second, attempt)
+	m.log.Info("scheduling retry", "task_id", t.ID, "request_id", t.RequestID, "attempt", attempt, "delay", delay)
+
+	go func() {
+		select {
+		case <-m.done:
+			return
+		case <-time.After(delay):
+		}
+		t.mu.Lock()
+		t.Status = StatusQueued
+		t.Error = ""
+		t.UpdatedAt = time.Now()
+		t.mu.Unlock()
+		m.broadcast(t)
+		if !m.sendQueue(t.ID, priority) {
+			m.failTask(t, fmt.Errorf("queue full or shutting down, try again later"))
+		}
+	}()
+}
+
+// reportToSentry sends a yt-dlp/backend failure to Sentry (a no-op if
+// SENTRY_DSN wasn't set, since sentry.Init leaves the SDK disabled rather
+// than erroring) tagged with the extractor/site and resolved yt-dlp
+// version, so an operator can tell "every youtube download is failing
+// since the 2024.08.06 update" apart from one-off link rot.
+func (m *Manager) reportToSentry(t *Task, site, backend string, err error) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetTag("site", site)
+		scope.SetTag("backend", backend)
+		scope.SetTag("ytdlp_version", m.ytdlpVersion())
+		scope.SetContext("task", map[string]interface{}{
+			"task_id":    t.ID,
+			"request_id": t.RequestID,
+			"url":        t.URL,
+		})
+		sentry.CaptureException(err)
+	})
 }
 
 func (m *Manager) failTask(t *Task, err error) {
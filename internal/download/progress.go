@@ -35,18 +35,49 @@ var (
 
 	reAlreadyDl = regexp.MustCompile(
 		`\[download\]\s+(.+)\s+has already been downloaded`)
+
+	reByteSize = regexp.MustCompile(`(?i)^([\d.]+)\s*(B|KiB|MiB|GiB|TiB)$`)
 )
 
+// byteUnits maps the unit suffixes yt-dlp's --newline output (and
+// humanBytes, its inverse) use onto their byte multiplier.
+var byteUnits = map[string]float64{
+	"B": 1, "KiB": 1024, "MiB": 1024 * 1024, "GiB": 1024 * 1024 * 1024, "TiB": 1024 * 1024 * 1024 * 1024,
+}
+
+// parseHumanBytes parses a yt-dlp pretty size like "64.00MiB" into a byte
+// count, for turning the percent-of-total progress lines already parsed
+// here into the absolute byte counter bandwidth.go samples from.
+func parseHumanBytes(s string) (float64, bool) {
+	m := reByteSize.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	mult, ok := byteUnits[m[2]]
+	if !ok {
+		return 0, false
+	}
+	return n * mult, true
+}
+
 // ParseLine inspects a single line of yt-dlp output and updates the task.
 // Returns true if a progress-relevant field changed (caller should broadcast).
 func ParseLine(line string, t *Task) bool {
 	// Progress percentage line
 	if m := reProgress.FindStringSubmatch(line); m != nil {
 		t.Progress = m[1] + "%"
-		if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+		pct, pctErr := strconv.ParseFloat(m[1], 64)
+		if pctErr == nil {
 			t.Percent = pct
 		}
 		t.Size = strings.TrimSpace(m[2])
+		if total, ok := parseHumanBytes(t.Size); ok && pctErr == nil {
+			t.setBytesTotal(int64(pct / 100 * total))
+		}
 		if m[3] != "" {
 			t.Speed = strings.TrimSpace(m[3])
 		}
@@ -63,6 +94,9 @@ func ParseLine(line string, t *Task) bool {
 		t.Size = strings.TrimSpace(m[1])
 		t.Speed = ""
 		t.ETA = "done"
+		if total, ok := parseHumanBytes(t.Size); ok {
+			t.setBytesTotal(int64(total))
+		}
 		return true
 	}
 
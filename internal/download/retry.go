@@ -0,0 +1,82 @@
+package download
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// transientErrorPatterns match stderr output that indicates a transient
+// failure worth retrying, as opposed to a permanent one (bad URL, private
+// video, unsupported site) that would just fail again identically.
+var transientErrorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`HTTP Error 429`),
+	regexp.MustCompile(`HTTP Error 5\d\d`),
+	regexp.MustCompile(`(?i)unable to download webpage`),
+	regexp.MustCompile(`(?i)connection reset`),
+	regexp.MustCompile(`(?i)connection refused`),
+	regexp.MustCompile(`(?i)timed out`),
+	regexp.MustCompile(`(?i)temporary failure in name resolution`),
+	regexp.MustCompile(`(?i)network is unreachable`),
+}
+
+// isTransient inspects recent stderr/log lines to decide whether a failure
+// is worth retrying automatically.
+func isTransient(logLines []string) bool {
+	// Only the tail is relevant — the real error is usually the last few lines.
+	start := 0
+	if len(logLines) > 20 {
+		start = len(logLines) - 20
+	}
+	for _, line := range logLines[start:] {
+		for _, re := range transientErrorPatterns {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backoffDelay computes an exponential backoff with jitter for retry attempt
+// n (1-indexed): base*2^(n-1), capped at max, plus up to 20% random jitter.
+func backoffDelay(base, max time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = 2 * time.Second
+	}
+	if max <= 0 {
+		max = 60 * time.Second
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			delay = max
+			break
+		}
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1)) // up to ~20%
+	return delay + jitter
+}
+
+// shouldRetry reports whether t just failed in a way worth retrying
+// automatically: it has retry budget left, and either its recent logs match
+// a known-transient failure pattern or its exit code/error message does,
+// rather than both signals pointing to a permanent failure.
+func (t *Task) shouldRetry() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Status != StatusFailed || t.RetryCount >= t.MaxRetries {
+		return false
+	}
+	return isTransient(t.Logs) || exitCodeIsTransient(t.Error)
+}
+
+// exitCodeIsTransient reports whether a yt-dlp process exit code is one it
+// uses for recoverable conditions rather than a hard usage/extractor error.
+// yt-dlp itself doesn't document stable transient-vs-permanent exit codes, so
+// this is advisory only — isTransient's stderr scan is the primary signal.
+func exitCodeIsTransient(exitErrMsg string) bool {
+	return strings.Contains(exitErrMsg, "signal: killed") || strings.Contains(exitErrMsg, "exit status 1")
+}
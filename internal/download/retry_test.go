@@ -0,0 +1,94 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		name string
+		logs []string
+		want bool
+	}{
+		{"empty", nil, false},
+		{"permanent error", []string{"ERROR: Unsupported URL"}, false},
+		{"429", []string{"some output", "HTTP Error 429: Too Many Requests"}, true},
+		{"5xx", []string{"HTTP Error 503: Service Unavailable"}, true},
+		{"connection reset", []string{"Connection reset by peer"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransient(tc.logs); got != tc.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tc.logs, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsTransientOnlyScansTail(t *testing.T) {
+	logs := make([]string, 0, 25)
+	logs = append(logs, "HTTP Error 429: Too Many Requests")
+	for i := 0; i < 24; i++ {
+		logs = append(logs, "unrelated output line")
+	}
+	if isTransient(logs) {
+		t.Error("isTransient() = true, want false once the transient line scrolls out of the 20-line tail")
+	}
+}
+
+func TestExitCodeIsTransient(t *testing.T) {
+	cases := []struct {
+		msg  string
+		want bool
+	}{
+		{"", false},
+		{"exit status 2", false},
+		{"exit status 1", true},
+		{"signal: killed", true},
+	}
+	for _, tc := range cases {
+		if got := exitCodeIsTransient(tc.msg); got != tc.want {
+			t.Errorf("exitCodeIsTransient(%q) = %v, want %v", tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 2 * time.Second
+	max := 60 * time.Second
+
+	d1 := backoffDelay(base, max, 1)
+	if d1 < base || d1 >= base+base/5+1 {
+		t.Errorf("backoffDelay(attempt=1) = %v, want in [%v, %v)", d1, base, base+base/5+1)
+	}
+
+	d3 := backoffDelay(base, max, 3)
+	want3 := 8 * time.Second
+	if d3 < want3 || d3 >= want3+want3/5+1 {
+		t.Errorf("backoffDelay(attempt=3) = %v, want in [%v, %v)", d3, want3, want3+want3/5+1)
+	}
+
+	dCapped := backoffDelay(base, max, 10)
+	if dCapped < max || dCapped >= max+max/5+1 {
+		t.Errorf("backoffDelay(attempt=10) = %v, want capped near %v", dCapped, max)
+	}
+}
+
+func TestTransferKey(t *testing.T) {
+	a := transferKey("https://example.com/v", []string{"--format", "best", "-o", "out.mp4"})
+	b := transferKey("https://example.com/v", []string{"-o", "out.mp4", "--format", "best"})
+	if a != b {
+		t.Errorf("transferKey() differed by flag order: %q vs %q", a, b)
+	}
+
+	c := transferKey("https://example.com/v", []string{"--format", "worst", "-o", "out.mp4"})
+	if a == c {
+		t.Error("transferKey() matched for different --format values, want distinct keys")
+	}
+
+	d := transferKey("https://example.com/other", []string{"--format", "best", "-o", "out.mp4"})
+	if a == d {
+		t.Error("transferKey() matched for different URLs, want distinct keys")
+	}
+}
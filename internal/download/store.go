@@ -0,0 +1,18 @@
+package download
+
+// Store persists Task state outside the in-memory map so queued, paused,
+// and running jobs survive a process restart instead of silently vanishing
+// — the same problem syncthing solves by keeping puller state on disk.
+type Store interface {
+	// Save upserts t's current state. It's called on every broadcast,
+	// including per-progress-tick updates, so implementations should
+	// throttle actual disk writes for a task that hasn't changed status.
+	Save(t *Task) error
+	// Load returns every persisted task. Order is not guaranteed; callers
+	// needing stable ordering should sort by CreatedAt.
+	Load() ([]*Task, error)
+	// Delete removes a task's persisted record.
+	Delete(id string) error
+	// Close releases the underlying database handle.
+	Close() error
+}
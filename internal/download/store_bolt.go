@@ -0,0 +1,109 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var tasksBucket = []byte("tasks")
+
+// persistInterval bounds how often a task that's only changed progress
+// (not status) is actually written to disk — a running download can emit
+// several broadcasts a second, and fsyncing every one of them would turn
+// normal operation into an IO storm.
+const persistInterval = time.Second
+
+// BoltStore is the default Store: one local BoltDB file under ConfigDir.
+type BoltStore struct {
+	db *bolt.DB
+
+	mu         sync.Mutex
+	lastWrite  map[string]time.Time
+	lastStatus map[string]TaskStatus
+}
+
+// NewBoltStore opens (creating if needed) a BoltDB file at path and ensures
+// the tasks bucket exists.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open task store %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init task store %s: %w", path, err)
+	}
+	return &BoltStore{
+		db:         db,
+		lastWrite:  make(map[string]time.Time),
+		lastStatus: make(map[string]TaskStatus),
+	}, nil
+}
+
+func (s *BoltStore) Save(t *Task) error {
+	// t.mu stays held for the status check AND the marshal below — matching
+	// the convention Snapshot()/AddLog() already use — since runTransfer and
+	// friends mutate these same exported fields concurrently through t.mu;
+	// releasing it between the two would let json.Marshal read a Task
+	// that's being written to on another goroutine. It's released again
+	// before the actual disk write so an fsync never blocks progress updates.
+	t.mu.Lock()
+	status := t.Status
+
+	s.mu.Lock()
+	last, seen := s.lastWrite[t.ID]
+	statusChanged := s.lastStatus[t.ID] != status
+	if seen && !statusChanged && time.Since(last) < persistInterval {
+		s.mu.Unlock()
+		t.mu.Unlock()
+		return nil
+	}
+	s.lastWrite[t.ID] = time.Now()
+	s.lastStatus[t.ID] = status
+	s.mu.Unlock()
+
+	data, err := json.Marshal(t)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte(t.ID), data)
+	})
+}
+
+func (s *BoltStore) Load() ([]*Task, error) {
+	var out []*Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(k, v []byte) error {
+			var t Task
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("decode task %s: %w", k, err)
+			}
+			out = append(out, &t)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (s *BoltStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.lastWrite, id)
+	delete(s.lastStatus, id)
+	s.mu.Unlock()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -4,12 +4,24 @@ import (
 	"context"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"crypto/rand"
 	"encoding/hex"
 )
 
+// TaskOptions configures scheduling/retry behavior for a new Task, beyond the
+// url+args every task needs.
+type TaskOptions struct {
+	Priority     Priority      // default PriorityNormal
+	MaxRetries   int           // default 0 (no automatic retry)
+	RetryBackoff time.Duration // base backoff delay; default 2s, see backoffDelay
+	Backend      string        // "auto"|"ytdlp"|"aria2"|"http"; default "auto"
+	RateLimit    int64         // bytes/sec cap for this task; 0 means unlimited (still subject to the global cap)
+	RequestID    string        // the HTTP request ID (see requestid middleware) that created this task, for log correlation
+}
+
 // TaskStatus represents the lifecycle state of a download task.
 type TaskStatus string
 
@@ -28,8 +40,8 @@ type Task struct {
 	URL       string     `json:"url"`
 	Title     string     `json:"title"`
 	Status    TaskStatus `json:"status"`
-	Progress  string     `json:"progress"`   // e.g. "45.2%"
-	Percent   float64    `json:"percent"`     // 0–100 numeric for progress bar
+	Progress  string     `json:"progress"` // e.g. "45.2%"
+	Percent   float64    `json:"percent"`  // 0–100 numeric for progress bar
 	Size      string     `json:"size"`
 	Speed     string     `json:"speed"`
 	ETA       string     `json:"eta"`
@@ -40,10 +52,36 @@ type Task struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 
+	// Scheduling/retry state (the "transfer manager" subsystem).
+	Priority     Priority      `json:"priority"`
+	MaxRetries   int           `json:"max_retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+	RetryCount   int           `json:"retry_count"`
+
+	// Backend selects which Backend implementation runs this transfer
+	// ("auto"|"ytdlp"|"aria2"|"http"); resolved to a concrete name once
+	// execution starts.
+	Backend string `json:"backend"`
+
+	// RateLimit is this task's own byte/sec cap (0 = unlimited), set via
+	// TaskOptions or POST /api/tasks/:id/limit; it's independent of — and
+	// layered under — the manager's global cap.
+	RateLimit int64 `json:"rate_limit"`
+
+	// RequestID correlates this task's log lines back to the HTTP request
+	// that submitted it (see the requestid middleware in main.go).
+	RequestID string `json:"request_id,omitempty"`
+
 	// Internal — not serialized
-	cmd    *exec.Cmd          `json:"-"`
-	cancel context.CancelFunc `json:"-"`
-	mu     sync.Mutex         `json:"-"`
+	cmd           *exec.Cmd          `json:"-"`
+	cancel        context.CancelFunc `json:"-"`
+	transferKey   string             `json:"-"` // set by Manager.execute; identifies the shared Transfer, if any
+	aria2GID      string             `json:"-"` // set by Aria2Backend; lets Cancel issue aria2.remove
+	logWriter     *taskLogWriter     `json:"-"` // set by Manager.execute; mirrors AddLog to ConfigDir/logs/<id>.log
+	limiter       *tokenBucket       `json:"-"` // set by Manager.wireLimiter; backs RateLimit for backends that can throttle mid-flight
+	globalLimiter *tokenBucket       `json:"-"` // set by Manager.wireLimiter; shared across every task, backs cfg.MaxBytesPerSec
+	bytesTotal    int64              `json:"-"` // atomic; absolute bytes downloaded so far this attempt, see BytesTotal
+	mu            sync.Mutex         `json:"-"`
 }
 
 func randomID() string {
@@ -52,19 +90,44 @@ func randomID() string {
 	return hex.EncodeToString(b)
 }
 
-// NewTask creates a queued task ready for submission.
+// NewTask creates a queued task with default scheduling options (normal
+// priority, no automatic retries) ready for submission.
 func NewTask(url string, args []string) *Task {
+	return NewTaskWithOptions(url, args, TaskOptions{})
+}
+
+// NewTaskWithOptions creates a queued task with explicit priority/retry
+// behavior, e.g. for the Submit(t, opts) surface the transfer scheduler exposes.
+func NewTaskWithOptions(url string, args []string, opts TaskOptions) *Task {
+	priority := opts.Priority
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	backoff := opts.RetryBackoff
+	if backoff == 0 {
+		backoff = 2 * time.Second
+	}
+	backend := opts.Backend
+	if backend == "" {
+		backend = "auto"
+	}
 	return &Task{
-		ID:        randomID(),
-		URL:       url,
-		Title:     url, // will be overwritten when yt-dlp emits metadata
-		Status:    StatusQueued,
-		Progress:  "0%",
-		Percent:   0,
-		Args:      args,
-		Logs:      make([]string, 0, 64),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:           randomID(),
+		URL:          url,
+		Title:        url, // will be overwritten when yt-dlp emits metadata
+		Status:       StatusQueued,
+		Progress:     "0%",
+		Percent:      0,
+		Args:         args,
+		Logs:         make([]string, 0, 64),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Priority:     priority,
+		MaxRetries:   opts.MaxRetries,
+		RetryBackoff: backoff,
+		Backend:      backend,
+		RateLimit:    opts.RateLimit,
+		RequestID:    opts.RequestID,
 	}
 }
 
@@ -78,10 +141,15 @@ func (t *Task) Snapshot() map[string]interface{} {
 		"size": t.Size, "speed": t.Speed, "eta": t.ETA,
 		"filename": t.Filename, "error": t.Error, "logs": t.Logs,
 		"args": t.Args, "created_at": t.CreatedAt, "updated_at": t.UpdatedAt,
+		"priority": t.Priority, "max_retries": t.MaxRetries, "retry_count": t.RetryCount,
+		"backend": t.Backend, "rate_limit": t.RateLimit, "bytes_total": t.BytesTotal(),
+		"request_id": t.RequestID,
 	}
 }
 
-// AddLog appends a line, capped at 500 entries to bound memory.
+// AddLog appends a line to the in-memory ring buffer (capped at 500 entries)
+// and, if a log file has been opened for this task, to ConfigDir/logs/<id>.log
+// so full history survives past what the ring buffer can hold in RAM.
 func (t *Task) AddLog(line string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -90,4 +158,51 @@ func (t *Task) AddLog(line string) {
 		t.Logs = t.Logs[len(t.Logs)-500:]
 	}
 	t.UpdatedAt = time.Now()
+	if t.logWriter != nil {
+		t.logWriter.WriteLine(line)
+	}
+}
+
+// BytesTotal returns the absolute byte count downloaded so far this
+// attempt. Read via atomic, not t.mu, since the 1-second bandwidth sampler
+// polls every task and shouldn't contend with the lock ParseLine/AddLog
+// hold far more often.
+func (t *Task) BytesTotal() int64 {
+	return atomic.LoadInt64(&t.bytesTotal)
+}
+
+// setBytesTotal overwrites the byte count outright — used by every backend:
+// yt-dlp and aria2 report an absolute "bytes so far" figure each time they
+// report progress, and HTTPBackend passes its own running total of bytes
+// written.
+func (t *Task) setBytesTotal(n int64) {
+	atomic.StoreInt64(&t.bytesTotal, n)
+}
+
+// addBytes adds a delta to the byte count, for a backend that only sees
+// each chunk as it's read off the wire. No backend in this package calls
+// it; HTTPBackend keeps its own total and uses setBytesTotal.
+func (t *Task) addBytes(n int64) {
+	atomic.AddInt64(&t.bytesTotal, n)
+}
+
+// throttle blocks until n bytes' worth of budget is available from both the
+// task's own limiter and the manager's shared global limiter (if either is
+// set), so a backend's read loop can rate-limit itself without knowing
+// anything about where the limits came from.
+func (t *Task) throttle(ctx context.Context, n int) error {
+	t.mu.Lock()
+	limiter, global := t.limiter, t.globalLimiter
+	t.mu.Unlock()
+	if global != nil {
+		if err := global.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	if limiter != nil {
+		if err := limiter.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,82 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxTaskLogBytes is the size a per-task log file rotates at — generous
+// enough that a normal download never hits it, but bounded so a task that
+// gets retried over and over doesn't grow a file forever.
+const maxTaskLogBytes = 10 * 1024 * 1024
+
+// taskLogWriter appends one task's log lines to ConfigDir/logs/<id>.log (in
+// addition to the in-memory 500-line ring buffer on Task itself), rotating
+// the current file to a ".1" suffix once it passes maxTaskLogBytes.
+type taskLogWriter struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	size int64
+}
+
+func newTaskLogWriter(logsDir, taskID string) (*taskLogWriter, error) {
+	if err := os.MkdirAll(logsDir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(logsDir, taskID+".log")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	return &taskLogWriter{path: path, f: f, size: size}, nil
+}
+
+// WriteLine appends line, rotating first if the file has grown past
+// maxTaskLogBytes.
+func (w *taskLogWriter) WriteLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return
+	}
+	if w.size > maxTaskLogBytes {
+		w.rotate()
+		if w.f == nil {
+			return
+		}
+	}
+	n, err := fmt.Fprintln(w.f, line)
+	if err == nil {
+		w.size += int64(n)
+	}
+}
+
+func (w *taskLogWriter) rotate() {
+	w.f.Close()
+	_ = os.Rename(w.path, w.path+".1")
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		w.f = nil
+		return
+	}
+	w.f = f
+	w.size = 0
+}
+
+func (w *taskLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
@@ -0,0 +1,174 @@
+package download
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Priority controls scheduling order: high-priority tasks are dequeued
+// before normal, normal before low. Within a priority tier, order is FIFO.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// transferKey canonically identifies "the same download" — same URL, same
+// selected format, same output template — so concurrent submissions of the
+// same transfer share one yt-dlp process instead of racing each other.
+func transferKey(url string, args []string) string {
+	format := ""
+	output := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format", "-f":
+			if i+1 < len(args) {
+				format = args[i+1]
+			}
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+			}
+		}
+	}
+	return strings.Join([]string{url, format, output}, "\x1f")
+}
+
+// Transfer is the low-level unit of work: one yt-dlp process backing
+// potentially many subscribed Tasks. Tasks that target the same transferKey
+// subscribe to the same process and receive its progress broadcasts.
+// Cancelling a task that joined an existing transfer only unsubscribes it;
+// the process keeps running while the task that started it (the primary)
+// is still subscribed. Cancelling the primary kills the process at once,
+// and the other subscribers then mirror its cancelled state.
+type Transfer struct {
+	key         string
+	mu          sync.Mutex
+	cmd         *exec.Cmd
+	cancel      context.CancelFunc
+	primary     *Task            // the Task whose process is actually running
+	done        chan struct{}    // closed once the process exits
+	subscribers map[string]*Task // task ID -> task
+	// retrySubs snapshots every non-primary subscriber right before done is
+	// closed (see runTransfer's final defer) — after that point subscribers
+	// unsubscribe themselves via waitForTransfer, so this is execute's only
+	// chance to learn who else needs to be re-queued if the primary retries.
+	retrySubs []*Task
+}
+
+func newTransfer(key string) *Transfer {
+	return &Transfer{key: key, subscribers: make(map[string]*Task), done: make(chan struct{})}
+}
+
+// subscribe adds t as a subscriber and returns the current subscriber count.
+func (tr *Transfer) subscribe(t *Task) int {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.subscribers[t.ID] = t
+	return len(tr.subscribers)
+}
+
+// unsubscribe removes t and returns how many subscribers remain — callers
+// use remaining == 0 to decide whether to actually cancel the underlying process.
+func (tr *Transfer) unsubscribe(id string) (remaining int) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	delete(tr.subscribers, id)
+	return len(tr.subscribers)
+}
+
+// snapshotSubscribers returns the subscribed tasks at this instant.
+func (tr *Transfer) snapshotSubscribers() []*Task {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	out := make([]*Task, 0, len(tr.subscribers))
+	for _, t := range tr.subscribers {
+		out = append(out, t)
+	}
+	return out
+}
+
+// transferRegistry deduplicates concurrent Submit calls that target the same
+// transferKey, and is the "low-level" half of Manager's two-layer design —
+// the scheduler (Manager's priority queues) decides *when* a transfer runs,
+// the registry decides *whether a new process is needed at all*.
+type transferRegistry struct {
+	mu        sync.Mutex
+	transfers map[string]*Transfer
+}
+
+func newTransferRegistry() *transferRegistry {
+	return &transferRegistry{transfers: make(map[string]*Transfer)}
+}
+
+// joinOrCreate returns the existing in-flight Transfer for key if one is
+// running, subscribing t to it; otherwise it creates and registers a new one
+// with t as its sole subscriber. The second return value reports whether t
+// should start a new yt-dlp process (false means it's piggybacking on an
+// existing one).
+func (r *transferRegistry) joinOrCreate(key string, t *Task) (tr *Transfer, shouldStart bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.transfers[key]; ok {
+		existing.subscribe(t)
+		return existing, false
+	}
+	tr = newTransfer(key)
+	tr.subscribe(t)
+	r.transfers[key] = tr
+	return tr, true
+}
+
+// release unsubscribes t from its transfer, removing the transfer from the
+// registry entirely once it has no subscribers left. Used on normal
+// completion, where the process is already finished — nothing to cancel.
+func (r *transferRegistry) release(key, taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tr, ok := r.transfers[key]
+	if !ok {
+		return
+	}
+	if remaining := tr.unsubscribe(taskID); remaining == 0 {
+		delete(r.transfers, key)
+	}
+}
+
+// cancelSubscriber unsubscribes t and, only if no subscriber is left,
+// cancels the underlying process. The primary stays subscribed for as long
+// as its process runs, so this never stops a process that is still running
+// for its primary; Manager.Cancel kills a primary's process directly.
+func (r *transferRegistry) cancelSubscriber(key, taskID string) {
+	r.mu.Lock()
+	tr, ok := r.transfers[key]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	remaining := tr.unsubscribe(taskID)
+	if remaining == 0 {
+		delete(r.transfers, key)
+	}
+	r.mu.Unlock()
+
+	if remaining == 0 {
+		tr.mu.Lock()
+		cancel := tr.cancel
+		tr.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+// get returns the in-flight Transfer for key, if any.
+func (r *transferRegistry) get(key string) (*Transfer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tr, ok := r.transfers[key]
+	return tr, ok
+}
@@ -1,38 +1,121 @@
 package handler
 
 import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"yt-dlp-web/internal/config"
+	"yt-dlp-web/internal/cookies"
 	"yt-dlp-web/internal/download"
+	"yt-dlp-web/internal/logging"
+	"yt-dlp-web/internal/metadata"
 	"yt-dlp-web/internal/params"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/cache"
 )
 
+// requestID returns the ID requestid.New() (see main.go) stashed in
+// c.Locals under its default ContextKey ("requestid") — the middleware
+// exposes no FromContext accessor, just the raw Locals key.
+func requestID(c *fiber.Ctx) string {
+	return fmt.Sprint(c.Locals("requestid"))
+}
+
+// sessionCookieName is the opaque per-browser session ID cookies jars are
+// scoped under, so one client's uploaded jar can never be resolved by another.
+const sessionCookieName = "ytdlp_session"
+
 // API holds references to the download manager.
 type API struct {
-	mgr *download.Manager
+	mgr      *download.Manager
+	cookies  *cookies.Store
+	cfg      *config.Config
+	apiKeyed bool // true when API_KEY auth is enabled; disables response caching to avoid cross-tenant leakage
+	log      *slog.Logger
+	limiter  *requestLimiter // nil when cfg.RateLimitRPM == 0
+}
+
+// NewAPI creates the API handler. apiKeyed should match whether API_KEY
+// auth is wired in main.go — see the tasksCache Next func below.
+func NewAPI(mgr *download.Manager, cookiesStore *cookies.Store, cfg *config.Config, apiKeyed bool) *API {
+	a := &API{mgr: mgr, cookies: cookiesStore, cfg: cfg, apiKeyed: apiKeyed, log: logging.Named("api")}
+	if cfg.RateLimitRPM > 0 {
+		a.limiter = newRequestLimiter(cfg.RateLimitRPM, cfg.RateLimitBurst)
+	}
+	return a
 }
 
-// NewAPI creates the API handler.
-func NewAPI(mgr *download.Manager) *API {
-	return &API{mgr: mgr}
+// rateLimit gates yt-dlp-triggering endpoints (expensive, and the obvious
+// abuse target on an exposed instance) behind a requestLimiter keyed by
+// X-API-Key when present, else client IP — IP resolution honors
+// X-Forwarded-For only when main.go's fiber.Config{TrustedProxies: ...} was
+// given a non-empty list, so this can't be spoofed by an untrusted peer.
+func (a *API) rateLimit(c *fiber.Ctx) error {
+	if a.limiter == nil {
+		return c.Next()
+	}
+	key := c.Get("X-API-Key")
+	if key == "" {
+		key = c.IP()
+	}
+	if ok, wait := a.limiter.allow(key); !ok {
+		retryAfter := int(wait.Seconds()) + 1
+		c.Set("Retry-After", strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":               "rate limit exceeded",
+			"retry_after_seconds": retryAfter,
+		})
+	}
+	return c.Next()
 }
 
 // RegisterRoutes defines all REST endpoints.
 func (a *API) RegisterRoutes(app *fiber.App) {
 	api := app.Group("/api")
 
-	api.Post("/download", a.submitDownload)
-	api.Get("/tasks", a.listTasks)
+	// GET /api/tasks is the one read-heavy, poll-friendly endpoint worth
+	// caching — but only while nothing is running (a stale progress
+	// snapshot is worse than no cache) and only without API_KEY auth
+	// (caching a response keyed by path alone would leak one tenant's
+	// task list to the next request that hits the same cache key).
+	tasksCache := cache.New(cache.Config{
+		Expiration:   a.cfg.TasksCacheTTL,
+		CacheControl: true,
+		Next: func(c *fiber.Ctx) bool {
+			return a.apiKeyed || a.mgr.Stats()["running"] > 0
+		},
+	})
+
+	api.Post("/download", a.rateLimit, a.submitDownload)
+	api.Get("/tasks", tasksCache, a.listTasks)
 	api.Post("/tasks/:id/cancel", a.cancelTask)
 	api.Post("/tasks/:id/pause", a.pauseTask)
 	api.Post("/tasks/:id/resume", a.resumeTask)
 	api.Post("/tasks/:id/retry", a.retryTask)
+	api.Post("/tasks/:id/priority", a.setPriority)
+	api.Get("/tasks/:id/logs", a.taskLogs)
+	api.Get("/download/:id/file", a.downloadFile)
+	api.Post("/tasks/:id/limit", a.setTaskLimit)
 	api.Delete("/tasks/:id", a.deleteTask)
-	api.Post("/formats", a.listFormats)
+	api.Post("/formats", a.rateLimit, a.listFormats)
+	api.Post("/metadata", a.rateLimit, a.fetchMetadata)
+	api.Post("/cookies", a.uploadCookies)
+	api.Post("/policy/explain", a.explainPolicy)
 	api.Post("/clear-completed", a.clearCompleted)
 	api.Get("/stats", a.stats)
+	api.Get("/bandwidth", a.bandwidth)
+	api.Post("/settings/global-limit", a.setGlobalLimit)
 }
 
 func (a *API) submitDownload(c *fiber.Ctx) error {
@@ -41,6 +124,14 @@ func (a *API) submitDownload(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request: " + err.Error()})
 	}
 
+	if req.CookiesJar != "" {
+		path, err := a.cookies.Path(sessionID(c), req.CookiesJar)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid cookies jar: " + err.Error()})
+		}
+		req.SetCookiesPath(path)
+	}
+
 	url, args := params.BuildArgs(&req)
 	if url == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "URL is required"})
@@ -49,7 +140,14 @@ func (a *API) submitDownload(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "URL must start with http:// or https://"})
 	}
 
-	task := download.NewTask(url, args)
+	task := download.NewTaskWithOptions(url, args, download.TaskOptions{
+		Priority:     download.Priority(c.Query("priority")),
+		MaxRetries:   c.QueryInt("max_retries", 0),
+		RetryBackoff: time.Duration(c.QueryInt("retry_backoff_seconds", 2)) * time.Second,
+		Backend:      c.Query("backend", "auto"),
+		RateLimit:    int64(c.QueryInt("rate_limit", 0)),
+		RequestID:    requestID(c),
+	})
 	a.mgr.Submit(task)
 	return c.JSON(fiber.Map{"ok": true, "task": task})
 }
@@ -86,6 +184,139 @@ func (a *API) retryTask(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"ok": true})
 }
 
+func (a *API) setPriority(c *fiber.Ctx) error {
+	var body struct {
+		Priority string `json:"priority"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Priority == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "priority is required"})
+	}
+	if err := a.mgr.SetPriority(c.Params("id"), download.Priority(body.Priority)); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// setTaskLimit changes a single task's own bandwidth cap at runtime; see
+// Manager.SetTaskLimit for how quickly each backend picks it up.
+func (a *API) setTaskLimit(c *fiber.Ctx) error {
+	var body struct {
+		BytesPerSec int64 `json:"bytes_per_sec"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request: " + err.Error()})
+	}
+	if err := a.mgr.SetTaskLimit(c.Params("id"), body.BytesPerSec); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// setGlobalLimit changes the manager-wide bandwidth cap at runtime.
+func (a *API) setGlobalLimit(c *fiber.Ctx) error {
+	var body struct {
+		BytesPerSec int64 `json:"bytes_per_sec"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request: " + err.Error()})
+	}
+	a.mgr.SetGlobalLimit(body.BytesPerSec)
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// bandwidth returns the most recently sampled manager-wide bandwidth
+// snapshot (aggregate + per-task rolling rates).
+func (a *API) bandwidth(c *fiber.Ctx) error {
+	return c.JSON(a.mgr.Bandwidth())
+}
+
+// downloadFile streams a task's on-disk output file with Range/If-Range
+// support (via net/http.ServeContent, so 206/Content-Range/If-Range all
+// follow RFC 7233 exactly rather than being hand-rolled), letting the
+// browser seek within a video preview or resume an interrupted client
+// download. The ETag is size+mtime, not a content hash — cheap to compute
+// and good enough to detect "the file on disk changed since you last asked".
+func (a *API) downloadFile(c *fiber.Ctx) error {
+	path, err := a.mgr.FilePath(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "output file not found on disk"})
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		a.log.Error("failed to stat output file", "request_id", requestID(c), "path", path, "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Accept-Ranges", "bytes")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filepath.Base(path)))
+
+	etag := fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+	name := filepath.Base(path)
+	modTime := info.ModTime()
+	return adaptor.HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ServeContent checks If-Range/If-None-Match against the ETag in
+		// w.Header(), which starts empty under the adaptor, so it goes here.
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, name, modTime, f)
+	})(c)
+}
+
+// taskLogs serves a task's full log history from its persisted log file
+// (falling back to the in-memory ring buffer if the file isn't available),
+// paginated by byte offset via ?offset=N so the UI can stream history
+// without holding it all in RAM. ?format=text returns plain text instead of
+// JSON; ?follow=1 upgrades to an SSE stream that polls for new lines once a
+// second, for tail -f-style following.
+func (a *API) taskLogs(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, ok := a.mgr.Get(id); !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	offset := int64(c.QueryInt("offset", 0))
+
+	if c.QueryBool("follow", false) {
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			pos := offset
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for range ticker.C {
+				lines, next, err := a.mgr.ReadTaskLog(id, pos)
+				if err != nil {
+					return
+				}
+				pos = next
+				for _, line := range lines {
+					if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+						return
+					}
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+		return nil
+	}
+
+	lines, next, err := a.mgr.ReadTaskLog(id, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if c.Query("format") == "text" {
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString(strings.Join(lines, "\n"))
+	}
+	return c.JSON(fiber.Map{"ok": true, "lines": lines, "next_offset": next})
+}
+
 func (a *API) deleteTask(c *fiber.Ctx) error {
 	if err := a.mgr.Delete(c.Params("id")); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
@@ -112,6 +343,94 @@ func (a *API) listFormats(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"ok": true, "output": out})
 }
 
+// fetchMetadata is the InfoOnly counterpart to /api/download: it runs
+// metadata.Fetch against url (same --dump-single-json invocation buildUI
+// injects when DownloadRequest.InfoOnly is set) and returns the parsed
+// Video/Playlist directly, instead of queuing a Task that writes the JSON
+// to a file nothing reads back.
+func (a *API) fetchMetadata(c *fiber.Ctx) error {
+	var body struct {
+		URL          string `json:"url"`
+		Args         string `json:"args"`
+		FlatPlaylist bool   `json:"flat_playlist"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "url required"})
+	}
+	if !strings.HasPrefix(body.URL, "http://") && !strings.HasPrefix(body.URL, "https://") {
+		return c.Status(400).JSON(fiber.Map{"error": "URL must start with http:// or https://"})
+	}
+	extra, sanitizeErr := params.SanitizeArgs(params.SplitShell(body.Args))
+	if sanitizeErr != nil {
+		a.log.Warn("metadata fetch: sanitize args", "request_id", requestID(c), "error", sanitizeErr)
+	}
+
+	out, err := metadata.NewFetcher(a.cfg).Fetch(body.URL, metadata.Options{
+		FlatPlaylist: body.FlatPlaylist,
+		ExtraArgs:    extra,
+	})
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+	if out.Playlist != nil {
+		return c.JSON(fiber.Map{"ok": true, "playlist": out.Playlist})
+	}
+	return c.JSON(fiber.Map{"ok": true, "video": out.Video})
+}
+
+// uploadCookies accepts a multipart "file" field containing a Netscape-format
+// cookies file, validates it, and returns an opaque jar ID scoped to the
+// caller's session. The jar ID (never a path) is what DownloadRequest.CookiesJar
+// carries back in a later /api/download call.
+func (a *API) uploadCookies(c *fiber.Ctx) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file field is required"})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "failed to open upload: " + err.Error()})
+	}
+	defer f.Close()
+
+	jarID, err := a.cookies.Save(sessionID(c), f)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"ok": true, "jar_id": jarID})
+}
+
+// sessionID returns the caller's opaque session ID, minting and setting one
+// via cookie if absent.
+func sessionID(c *fiber.Ctx) string {
+	if id := c.Cookies(sessionCookieName); id != "" {
+		return id
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		HTTPOnly: true,
+		SameSite: "Strict",
+	})
+	return id
+}
+
+// explainPolicy is a dry-run of the argument policy: it reports which flags
+// in the given args would be blocked, without actually running anything.
+func (a *API) explainPolicy(c *fiber.Ctx) error {
+	var body struct {
+		Args string `json:"args"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request: " + err.Error()})
+	}
+	violations := params.Explain(params.SplitShell(body.Args))
+	return c.JSON(fiber.Map{"ok": true, "violations": violations})
+}
+
 func (a *API) clearCompleted(c *fiber.Ctx) error {
 	n := a.mgr.ClearCompleted()
 	return c.JSON(fiber.Map{"ok": true, "cleared": n})
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"sync"
+	"time"
+)
+
+// requestLimiter is a per-key token bucket for rate-limiting HTTP requests —
+// the same token-bucket idea download.tokenBucket uses for bytes/sec, but
+// keyed per caller (API key if present, else client IP) instead of one
+// shared budget, since submission endpoints need to cap abuse per tenant,
+// not throttle a single shared resource.
+type requestLimiter struct {
+	mu      sync.Mutex
+	rps     float64
+	burst   float64
+	buckets map[string]*requestBucket
+}
+
+type requestBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketIdleTimeout bounds how long a key's bucket survives without a
+// request before sweepLoop reclaims it. The whole point of this limiter is
+// fending off many distinct abusive IPs/keys, so leaving buckets around
+// forever would turn the defense itself into the unbounded-memory-growth
+// problem it exists to prevent.
+const bucketIdleTimeout = 10 * time.Minute
+
+// newRequestLimiter builds a limiter allowing rpm requests/minute per key,
+// with burst as the largest number of requests a key may make back-to-back
+// before it has to wait on the steady-state rate.
+func newRequestLimiter(rpm, burst int) *requestLimiter {
+	l := &requestLimiter{
+		rps:     float64(rpm) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*requestBucket),
+	}
+	go l.sweepLoop()
+	return l
+}
+
+// sweepLoop periodically evicts buckets idle past bucketIdleTimeout, the
+// same way download.Manager.gcLoop ages out stale tasks.
+func (l *requestLimiter) sweepLoop() {
+	ticker := time.NewTicker(bucketIdleTimeout)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.sweepOnce()
+	}
+}
+
+func (l *requestLimiter) sweepOnce() {
+	cutoff := time.Now().Add(-bucketIdleTimeout)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if b.last.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// allow reports whether key may make a request right now, consuming one
+// token if so. If not, it also returns how long the caller should wait
+// before its next token is available, for a Retry-After header.
+func (l *requestLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &requestBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rps
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+}
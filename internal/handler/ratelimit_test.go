@@ -0,0 +1,42 @@
+package handler
+
+import "testing"
+
+func TestRequestLimiterAllowBurstThenThrottle(t *testing.T) {
+	l := newRequestLimiter(60, 3) // 1 req/sec, burst of 3
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("k"); !ok {
+			t.Fatalf("allow() call %d = false, want true within burst", i+1)
+		}
+	}
+	ok, wait := l.allow("k")
+	if ok {
+		t.Fatal("allow() = true after burst exhausted, want false")
+	}
+	if wait <= 0 {
+		t.Errorf("allow() wait = %v, want a positive Retry-After duration", wait)
+	}
+}
+
+func TestRequestLimiterAllowPerKey(t *testing.T) {
+	l := newRequestLimiter(60, 1)
+	if ok, _ := l.allow("a"); !ok {
+		t.Fatal("allow(a) = false, want true")
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Fatal("allow(a) second call = true, want false (burst of 1 exhausted)")
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Fatal("allow(b) = false, want true (separate key, separate bucket)")
+	}
+}
+
+func TestRequestLimiterSweepOnceEvictsIdleBuckets(t *testing.T) {
+	l := newRequestLimiter(60, 1)
+	l.allow("idle")
+	l.buckets["idle"].last = l.buckets["idle"].last.Add(-bucketIdleTimeout - 1)
+	l.sweepOnce()
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("sweepOnce() did not evict a bucket idle past bucketIdleTimeout")
+	}
+}
@@ -2,10 +2,11 @@ package handler
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"sync"
 
 	"yt-dlp-web/internal/download"
+	"yt-dlp-web/internal/logging"
 
 	"github.com/gofiber/contrib/websocket"
 )
@@ -14,12 +15,14 @@ import (
 type Hub struct {
 	clients map[*websocket.Conn]bool
 	mu      sync.Mutex
+	log     *slog.Logger
 }
 
 // NewHub creates and returns a new Hub.
 func NewHub() *Hub {
 	return &Hub{
 		clients: make(map[*websocket.Conn]bool),
+		log:     logging.Named("ws"),
 	}
 }
 
@@ -43,6 +46,29 @@ func (h *Hub) Unregister(c *websocket.Conn) {
 	h.mu.Unlock()
 }
 
+// BroadcastBandwidth sends the manager-wide bandwidth snapshot to all
+// connected clients, mirroring BroadcastTask.
+func (h *Hub) BroadcastBandwidth(b download.Bandwidth) {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      "bandwidth",
+		"bandwidth": b,
+	})
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if err := c.WriteMessage(websocket.TextMessage, data); err != nil {
+			h.log.Warn("write error, removing client", "error", err)
+			c.Close()
+			delete(h.clients, c)
+		}
+	}
+}
+
 // BroadcastTask sends a task update to all connected clients.
 // Uses a full Mutex (not RLock) to safely handle client removal on error.
 func (h *Hub) BroadcastTask(t *download.Task) {
@@ -59,7 +85,7 @@ func (h *Hub) BroadcastTask(t *download.Task) {
 
 	for c := range h.clients {
 		if err := c.WriteMessage(websocket.TextMessage, data); err != nil {
-			log.Printf("[ws] write error, removing client: %v", err)
+			h.log.Warn("write error, removing client", "error", err)
 			c.Close()
 			delete(h.clients, c)
 		}
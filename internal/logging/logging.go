@@ -0,0 +1,87 @@
+// Package logging provides the structured, per-subsystem loggers used
+// across the download/handler packages, replacing ad-hoc log.Printf calls.
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// baseLevel is LOG_LEVEL's slog.Level (debug|info|warn|error), same
+// convention as most Go services; defaults to info.
+var baseLevel = parseLevel(envOr("LOG_LEVEL", "info"))
+
+// jsonOutput is LOG_FORMAT=json|text; text (slog's human-readable k=v form)
+// is the default for local/interactive use, json for shipping to Loki/ELK.
+var jsonOutput = envOr("LOG_FORMAT", "text") == "json"
+
+// traced holds the subsystem names from YTDLP_WEB_TRACE, syncthing's
+// STTRACE convention ported over: a comma-separated list of subsystem names
+// ("download,download.ytdlp") whose logger is held at Debug regardless of
+// LOG_LEVEL, for the handful of lines too chatty to ever be on by default.
+var traced = parseTrace(os.Getenv("YTDLP_WEB_TRACE"))
+
+var (
+	mu      sync.Mutex
+	loggers = make(map[string]*slog.Logger)
+)
+
+// Named returns the "subsystem"-tagged logger for name, memoized so
+// repeated calls with the same name share one *slog.Logger (and handler).
+// Callers compose names with dots to mirror subsystem nesting, e.g.
+// Named("download.worker").
+func Named(name string) *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if l, ok := loggers[name]; ok {
+		return l
+	}
+
+	level := baseLevel
+	if traced[name] && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	if jsonOutput {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		h = slog.NewTextHandler(os.Stderr, opts)
+	}
+	l := slog.New(h).With("subsystem", name)
+	loggers[name] = l
+	return l
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug", "trace":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func parseTrace(v string) map[string]bool {
+	out := make(map[string]bool)
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out[s] = true
+		}
+	}
+	return out
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
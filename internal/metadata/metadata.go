@@ -0,0 +1,174 @@
+// Package metadata parses yt-dlp's `--dump-single-json` output into typed
+// Go structs, so callers can inspect formats, thumbnails, and playlist
+// entries without hand-parsing stdout themselves.
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"yt-dlp-web/internal/config"
+)
+
+// Thumbnail is a single thumbnail candidate reported by an extractor.
+type Thumbnail struct {
+	ID         string `json:"id,omitempty"`
+	URL        string `json:"url"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Preference int    `json:"preference,omitempty"`
+}
+
+// Subtitle is a single subtitle/caption track for one language.
+type Subtitle struct {
+	URL  string `json:"url"`
+	Ext  string `json:"ext,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Chapter is a named time range within a video.
+type Chapter struct {
+	Title     string  `json:"title,omitempty"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// Format is a single downloadable stream (video, audio, or muxed).
+type Format struct {
+	FormatID   string  `json:"format_id"`
+	Format     string  `json:"format,omitempty"`
+	Ext        string  `json:"ext,omitempty"`
+	Vcodec     string  `json:"vcodec,omitempty"`
+	Acodec     string  `json:"acodec,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	FPS        float64 `json:"fps,omitempty"`
+	FilesizeB  int64   `json:"filesize,omitempty"`
+	TBR        float64 `json:"tbr,omitempty"` // total bitrate, kbps
+	Protocol   string  `json:"protocol,omitempty"`
+	URL        string  `json:"url,omitempty"`
+	FormatNote string  `json:"format_note,omitempty"`
+}
+
+// Video describes a single extracted video (the common case).
+type Video struct {
+	ID          string                `json:"id"`
+	Title       string                `json:"title"`
+	Description string                `json:"description,omitempty"`
+	Uploader    string                `json:"uploader,omitempty"`
+	UploadDate  string                `json:"upload_date,omitempty"`
+	Duration    float64               `json:"duration,omitempty"`
+	WebpageURL  string                `json:"webpage_url,omitempty"`
+	Extractor   string                `json:"extractor,omitempty"`
+	Thumbnails  []Thumbnail           `json:"thumbnails,omitempty"`
+	Subtitles   map[string][]Subtitle `json:"subtitles,omitempty"`
+	Chapters    []Chapter             `json:"chapters,omitempty"`
+	Formats     []Format              `json:"formats,omitempty"`
+}
+
+// Playlist describes a `--flat-playlist` style result: a shallow list of
+// entries, each either a fully-resolved Video or a stub with just an ID/URL.
+type Playlist struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title,omitempty"`
+	Entries []Video `json:"entries"`
+}
+
+// YoutubeDlOutput is the parsed result of `--dump-single-json`. Exactly one
+// of Video or Playlist is non-nil, mirroring the `_type` field yt-dlp emits.
+type YoutubeDlOutput struct {
+	Video    *Video
+	Playlist *Playlist
+}
+
+// Options controls how Fetch invokes yt-dlp.
+type Options struct {
+	// FlatPlaylist avoids resolving every playlist entry (faster listing).
+	FlatPlaylist bool
+	// ExtraArgs are appended verbatim (e.g. --cookies, --proxy); callers are
+	// responsible for running them through params.SanitizeArgs first.
+	ExtraArgs []string
+	// Timeout bounds the yt-dlp invocation. Zero means 2 minutes.
+	Timeout time.Duration
+}
+
+// Fetcher runs yt-dlp in info-only mode using a shared Config, the same
+// pattern download.Manager uses to locate the binary and cache/config dirs.
+type Fetcher struct {
+	cfg *config.Config
+}
+
+// NewFetcher creates a Fetcher bound to cfg.
+func NewFetcher(cfg *config.Config) *Fetcher {
+	return &Fetcher{cfg: cfg}
+}
+
+// Fetch runs `yt-dlp --dump-single-json --no-download --skip-download` (plus
+// `--flat-playlist` when requested) against url and parses the result into
+// either a Video or a Playlist.
+func (f *Fetcher) Fetch(url string, opts Options) (*YoutubeDlOutput, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+
+	args := []string{"--no-colors", "--dump-single-json", "--no-download", "--skip-download"}
+	if opts.FlatPlaylist {
+		args = append(args, "--flat-playlist")
+	}
+	args = append(args, opts.ExtraArgs...)
+	args = append(args, url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	os.MkdirAll(filepath.Join(f.cfg.ConfigDir, "cache"), 0o755)
+	cmd := exec.CommandContext(ctx, f.cfg.YtDlpPath, args...)
+	cmd.Dir = f.cfg.DownloadDir
+	cmd.Env = append(os.Environ(),
+		"XDG_CACHE_HOME="+f.cfg.ConfigDir+"/cache",
+		"XDG_CONFIG_HOME="+f.cfg.ConfigDir,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("yt-dlp metadata fetch failed: %w: %s", err, stderr.String())
+	}
+
+	return Parse(stdout.Bytes())
+}
+
+// Parse decodes a single `--dump-single-json` document, dispatching on the
+// `_type` field yt-dlp includes for playlists ("playlist"/"multi_video").
+func Parse(data []byte) (*YoutubeDlOutput, error) {
+	var probe struct {
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("invalid yt-dlp json output: %w", err)
+	}
+
+	switch probe.Type {
+	case "playlist", "multi_video":
+		var pl Playlist
+		if err := json.Unmarshal(data, &pl); err != nil {
+			return nil, fmt.Errorf("invalid playlist json: %w", err)
+		}
+		return &YoutubeDlOutput{Playlist: &pl}, nil
+	default:
+		var v Video
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid video json: %w", err)
+		}
+		return &YoutubeDlOutput{Video: &v}, nil
+	}
+}
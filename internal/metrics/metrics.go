@@ -0,0 +1,61 @@
+// Package metrics holds the Prometheus collectors download.Manager updates
+// across a task's lifecycle (start, progress, complete, error), served at
+// GET /metrics behind the same API_KEY gate as the rest of the API.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics is registered against its own Registry rather than the global
+// default one, so /metrics reports exactly this project's collectors and
+// nothing an imported dependency happens to register on init.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	DownloadsStarted   *prometheus.CounterVec   // labels: site, format
+	DownloadsCompleted *prometheus.CounterVec   // labels: site, format, status
+	DownloadsFailed    *prometheus.CounterVec   // labels: site, format
+	BytesDownloaded    *prometheus.CounterVec   // labels: site, format
+	ActiveTasks        prometheus.Gauge         // currently running, across all sites/formats
+	DownloadDuration   *prometheus.HistogramVec // labels: site, format; seconds from start to terminal state
+}
+
+// New creates and registers every collector.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		DownloadsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ytdlp_web_downloads_started_total",
+			Help: "Total downloads started, by site and format.",
+		}, []string{"site", "format"}),
+		DownloadsCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ytdlp_web_downloads_completed_total",
+			Help: "Total downloads that reached a terminal state, by site, format, and status.",
+		}, []string{"site", "format", "status"}),
+		DownloadsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ytdlp_web_downloads_failed_total",
+			Help: "Total downloads that ended in failure, by site and format.",
+		}, []string{"site", "format"}),
+		BytesDownloaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ytdlp_web_bytes_downloaded_total",
+			Help: "Total bytes downloaded, by site and format.",
+		}, []string{"site", "format"}),
+		ActiveTasks: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ytdlp_web_active_tasks",
+			Help: "Number of download tasks currently running.",
+		}),
+		DownloadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ytdlp_web_download_duration_seconds",
+			Help:    "Time from a download starting to reaching a terminal state, by site and format.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12), // 1s .. ~34m
+		}, []string{"site", "format"}),
+	}
+	m.Registry.MustRegister(
+		m.DownloadsStarted,
+		m.DownloadsCompleted,
+		m.DownloadsFailed,
+		m.BytesDownloaded,
+		m.ActiveTasks,
+		m.DownloadDuration,
+	)
+	return m
+}
@@ -0,0 +1,113 @@
+package params
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// KV is a single `key=value` pair within one extractor's --extractor-args group.
+type KV struct {
+	Key   string
+	Value string
+}
+
+// ExtractorArgsBuilder composes properly-escaped `--extractor-args` values
+// for one or more extractors, so callers don't have to hand-craft
+// "youtube:player_client=android,web;po_token=..." strings and hope the
+// quoting survives SplitShell. Presets for common extractors live in the
+// youtube, twitch, and generic subpackages.
+type ExtractorArgsBuilder struct {
+	args map[string][]KV
+}
+
+// MarshalJSON renders args directly (e.g. {"youtube":[{"Key":"po_token","Value":"..."}]}),
+// so DownloadRequest.ExtractorArgsBuilder round-trips through a request body
+// the same way FormatSelector does.
+func (b *ExtractorArgsBuilder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.args)
+}
+
+// UnmarshalJSON is MarshalJSON's inverse.
+func (b *ExtractorArgsBuilder) UnmarshalJSON(data []byte) error {
+	b.args = make(map[string][]KV)
+	return json.Unmarshal(data, &b.args)
+}
+
+// NewExtractorArgsBuilder returns an empty builder.
+func NewExtractorArgsBuilder() *ExtractorArgsBuilder {
+	return &ExtractorArgsBuilder{args: make(map[string][]KV)}
+}
+
+// Set adds a key=value pair for the given extractor (e.g. "youtube").
+// Multiple calls for the same extractor/key append rather than replace,
+// matching yt-dlp's own comma-joined multi-value semantics.
+func (b *ExtractorArgsBuilder) Set(extractor string, kv KV) *ExtractorArgsBuilder {
+	b.args[extractor] = append(b.args[extractor], kv)
+	return b
+}
+
+// SetDefault adds kv for extractor only if no value has been set for that
+// key yet, letting presets merge with config-level defaults without
+// clobbering an explicit user choice.
+func (b *ExtractorArgsBuilder) SetDefault(extractor string, kv KV) *ExtractorArgsBuilder {
+	for _, existing := range b.args[extractor] {
+		if existing.Key == kv.Key {
+			return b
+		}
+	}
+	return b.Set(extractor, kv)
+}
+
+// Empty reports whether no extractor args have been configured.
+func (b *ExtractorArgsBuilder) Empty() bool {
+	return len(b.args) == 0
+}
+
+// Build renders the builder into the flag/value pairs to append to a yt-dlp
+// invocation: one "--extractor-args" "EXTRACTOR:k=v;k2=v2" pair per extractor,
+// sorted by extractor name for deterministic output.
+func (b *ExtractorArgsBuilder) Build() []string {
+	extractors := make([]string, 0, len(b.args))
+	for name := range b.args {
+		extractors = append(extractors, name)
+	}
+	sortStrings(extractors)
+
+	out := make([]string, 0, len(extractors)*2)
+	for _, name := range extractors {
+		groups := make(map[string][]string)
+		var order []string
+		for _, kv := range b.args[name] {
+			if _, ok := groups[kv.Key]; !ok {
+				order = append(order, kv.Key)
+			}
+			groups[kv.Key] = append(groups[kv.Key], escapeExtractorArgValue(kv.Value))
+		}
+		pairs := make([]string, 0, len(order))
+		for _, key := range order {
+			pairs = append(pairs, key+"="+strings.Join(groups[key], ","))
+		}
+		out = append(out, "--extractor-args", name+":"+strings.Join(pairs, ";"))
+	}
+	return out
+}
+
+// escapeExtractorArgValue backslash-escapes the characters that are
+// structurally significant in yt-dlp's extractor-args mini-language
+// (key=value groups separated by ";", multi-values joined by ",").
+func escapeExtractorArgValue(v string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`;`, `\;`,
+		`,`, `\,`,
+	)
+	return r.Replace(v)
+}
+
+func sortStrings(s []string) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && s[j-1] > s[j]; j-- {
+			s[j-1], s[j] = s[j], s[j-1]
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package params
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractorArgsBuilderBuild(t *testing.T) {
+	b := NewExtractorArgsBuilder()
+	b.Set("youtube", KV{Key: "player_client", Value: "android"}).
+		Set("youtube", KV{Key: "player_client", Value: "web"}).
+		Set("generic", KV{Key: "impersonate", Value: "chrome"})
+
+	got := b.Build()
+	want := []string{
+		"--extractor-args", "generic:impersonate=chrome",
+		"--extractor-args", "youtube:player_client=android,web",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Build() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Build()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractorArgsBuilderEscaping(t *testing.T) {
+	b := NewExtractorArgsBuilder()
+	b.Set("youtube", KV{Key: "po_token", Value: `a;b,c\d`})
+	got := b.Build()
+	want := `youtube:po_token=a\;b\,c\\d`
+	if len(got) != 2 || got[1] != want {
+		t.Errorf("Build() = %v, want [--extractor-args %q]", got, want)
+	}
+}
+
+func TestExtractorArgsBuilderSetDefaultDoesNotClobber(t *testing.T) {
+	b := NewExtractorArgsBuilder()
+	b.Set("youtube", KV{Key: "player_client", Value: "web"})
+	b.SetDefault("youtube", KV{Key: "player_client", Value: "android"})
+
+	got := b.Build()
+	want := "youtube:player_client=web"
+	if len(got) != 2 || got[1] != want {
+		t.Errorf("Build() = %v, want [--extractor-args %q]", got, want)
+	}
+}
+
+func TestExtractorArgsBuilderEmpty(t *testing.T) {
+	b := NewExtractorArgsBuilder()
+	if !b.Empty() {
+		t.Error("Empty() = false for a fresh builder, want true")
+	}
+	b.Set("youtube", KV{Key: "k", Value: "v"})
+	if b.Empty() {
+		t.Error("Empty() = true after Set, want false")
+	}
+}
+
+func TestExtractorArgsBuilderJSONRoundTrip(t *testing.T) {
+	b := NewExtractorArgsBuilder()
+	b.Set("youtube", KV{Key: "po_token", Value: "abc"})
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var out ExtractorArgsBuilder
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if out.Empty() {
+		t.Fatal("round-tripped builder is Empty(), want the youtube entry preserved")
+	}
+	got := out.Build()
+	want := "youtube:po_token=abc"
+	if len(got) != 2 || got[1] != want {
+		t.Errorf("round-tripped Build() = %v, want [--extractor-args %q]", got, want)
+	}
+}
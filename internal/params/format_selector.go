@@ -0,0 +1,155 @@
+package params
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatSelector composes a yt-dlp `--format`/`--format-sort` expression from
+// typed fields, so callers don't have to memorize yt-dlp's selector mini-language.
+// The zero value selects yt-dlp's own default behavior.
+type FormatSelector struct {
+	MaxHeight         int      // e.g. 1080
+	MaxFilesizeBytes  int64    // e.g. 500*1024*1024 for 500MB
+	PreferVcodec      string   // "av1", "vp9", "h264", ...
+	PreferAcodec      string   // "opus", "aac", ...
+	PreferFreeFormats bool     // prefer webm/opus/vp9 over proprietary codecs
+	Container         string   // desired final container, e.g. "mp4"
+	SortKeys          []string // raw --format-sort keys, e.g. "+size", "+br", "+res", "+fps"
+}
+
+// known-good vcodec/container and acodec/container pairings. yt-dlp can remux
+// most combinations via ffmpeg, but these are the ones that work without it.
+var compatibleVideoContainers = map[string][]string{
+	"av1":  {"mp4", "webm", "mkv"},
+	"vp9":  {"webm", "mkv", "mp4"},
+	"h264": {"mp4", "mkv"},
+	"avc1": {"mp4", "mkv"},
+	"vp8":  {"webm", "mkv"},
+}
+
+var compatibleAudioContainers = map[string][]string{
+	"opus":   {"webm", "ogg", "mkv"},
+	"aac":    {"mp4", "m4a", "mkv"},
+	"mp3":    {"mp3", "mp4", "mkv"},
+	"vorbis": {"ogg", "webm", "mkv"},
+}
+
+// Validate checks that the requested vcodec/acodec are compatible with the
+// requested Container, returning an error describing the mismatch if not.
+func (s *FormatSelector) Validate() error {
+	if s.Container == "" {
+		return nil
+	}
+	if s.PreferVcodec != "" {
+		if containers, ok := compatibleVideoContainers[strings.ToLower(s.PreferVcodec)]; ok && !contains(containers, s.Container) {
+			return fmt.Errorf("vcodec %q is not compatible with container %q (supported: %s)",
+				s.PreferVcodec, s.Container, strings.Join(containers, ", "))
+		}
+	}
+	if s.PreferAcodec != "" {
+		if containers, ok := compatibleAudioContainers[strings.ToLower(s.PreferAcodec)]; ok && !contains(containers, s.Container) {
+			return fmt.Errorf("acodec %q is not compatible with container %q (supported: %s)",
+				s.PreferAcodec, s.Container, strings.Join(containers, ", "))
+		}
+	}
+	return nil
+}
+
+// Build renders the selector into a `--format` expression. It composes a
+// fallback chain: the most constrained selector first, falling back to
+// progressively looser ones, ending in yt-dlp's own "best" selector.
+func (s *FormatSelector) Build() string {
+	var filters []string
+	if s.MaxHeight > 0 {
+		filters = append(filters, fmt.Sprintf("height<=%d", s.MaxHeight))
+	}
+	if s.MaxFilesizeBytes > 0 {
+		filters = append(filters, fmt.Sprintf("filesize<=%d", s.MaxFilesizeBytes))
+	}
+	if s.PreferVcodec != "" {
+		filters = append(filters, fmt.Sprintf("vcodec~='^%s'", s.PreferVcodec))
+	}
+	if s.PreferAcodec != "" {
+		filters = append(filters, fmt.Sprintf("acodec~='^%s'", s.PreferAcodec))
+	}
+
+	primary := "bestvideo*+bestaudio/best"
+	if len(filters) > 0 {
+		// yt-dlp reports acodec=none on video-only streams, so an acodec
+		// filter can never match a bestvideo[...] clause — it belongs on
+		// bestaudio[...] only. The muxed best[...] fallback keeps every
+		// filter since it selects a single combined stream.
+		videoFilters := filterVideoOnly(filters)
+		audioFilters := filterAcodecOnly(filters)
+		primary = fmt.Sprintf("%s+%s/%s+bestaudio/%s",
+			formatClause("bestvideo", videoFilters), formatClause("bestaudio", audioFilters),
+			formatClause("bestvideo", videoFilters), formatClause("best", filters))
+	}
+
+	chain := []string{primary}
+	if s.PreferFreeFormats {
+		chain = append(chain, "bestvideo*+bestaudio/best")
+	}
+	chain = append(chain, "best")
+
+	return strings.Join(dedupe(chain), "/")
+}
+
+// FormatSort renders SortKeys into a `--format-sort` value, e.g. "+size,+br,+res,+fps".
+func (s *FormatSelector) FormatSort() string {
+	return strings.Join(s.SortKeys, ",")
+}
+
+func filterAcodecOnly(filters []string) []string {
+	out := make([]string, 0, len(filters))
+	for _, f := range filters {
+		if strings.HasPrefix(f, "acodec") {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// filterVideoOnly drops the acodec filter, for selector clauses (bestvideo)
+// that only ever see video-only streams.
+func filterVideoOnly(filters []string) []string {
+	out := make([]string, 0, len(filters))
+	for _, f := range filters {
+		if !strings.HasPrefix(f, "acodec") {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// formatClause renders a yt-dlp selector like "bestvideo[h1][h2]", falling
+// back to the bare selector (no brackets, which yt-dlp rejects when empty)
+// when filters has nothing left for this clause.
+func formatClause(selector string, filters []string) string {
+	if len(filters) == 0 {
+		return selector
+	}
+	return fmt.Sprintf("%s[%s]", selector, strings.Join(filters, "]["))
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package params
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatSelectorBuildDefault(t *testing.T) {
+	s := &FormatSelector{}
+	got := s.Build()
+	want := "bestvideo*+bestaudio/best/best"
+	if got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatSelectorBuildFallbackChain(t *testing.T) {
+	s := &FormatSelector{MaxHeight: 1080, PreferFreeFormats: true}
+	got := s.Build()
+	parts := strings.Split(got, "/")
+	if parts[len(parts)-1] != "best" {
+		t.Errorf("Build() = %q, want chain ending in \"best\"", got)
+	}
+	if !strings.Contains(got, "bestvideo[height<=1080]") {
+		t.Errorf("Build() = %q, want a height<=1080 clause", got)
+	}
+	if !strings.Contains(got, "bestvideo*+bestaudio/best") {
+		t.Errorf("Build() = %q, want the free-formats fallback clause", got)
+	}
+}
+
+func TestFormatSelectorBuildAcodecScopedToAudioClauses(t *testing.T) {
+	s := &FormatSelector{PreferVcodec: "av1", PreferAcodec: "opus"}
+	got := s.Build()
+	for _, clause := range strings.FieldsFunc(got, func(r rune) bool { return r == '/' || r == '+' }) {
+		if strings.HasPrefix(clause, "bestvideo[") && strings.Contains(clause, "acodec") {
+			t.Errorf("Build() = %q, bestvideo clause %q should not carry an acodec filter", got, clause)
+		}
+	}
+	if !strings.Contains(got, "bestaudio[acodec~='^opus']") {
+		t.Errorf("Build() = %q, want acodec filter on the bestaudio clause", got)
+	}
+}
+
+func TestFormatSelectorValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		s       FormatSelector
+		wantErr bool
+	}{
+		{"no container is always fine", FormatSelector{PreferVcodec: "av1"}, false},
+		{"compatible vcodec/container", FormatSelector{PreferVcodec: "av1", Container: "mp4"}, false},
+		{"incompatible vcodec/container", FormatSelector{PreferVcodec: "h264", Container: "webm"}, true},
+		{"incompatible acodec/container", FormatSelector{PreferAcodec: "opus", Container: "mp4"}, true},
+		{"compatible acodec/container", FormatSelector{PreferAcodec: "aac", Container: "mp4"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.s.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
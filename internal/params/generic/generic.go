@@ -0,0 +1,20 @@
+// Package generic provides params.ExtractorArgsBuilder presets for yt-dlp's
+// generic (non-site-specific) extractor.
+package generic
+
+import "yt-dlp-web/internal/params"
+
+// ImpersonateTarget sets the `impersonate` extractor arg, which tells yt-dlp
+// to mimic a specific browser's TLS/HTTP fingerprint (requires curl_cffi),
+// e.g. ImpersonateTarget("chrome-120").
+func ImpersonateTarget(target string) params.KV {
+	return params.KV{Key: "impersonate", Value: target}
+}
+
+// Apply sets the given generic extractor args on b.
+func Apply(b *params.ExtractorArgsBuilder, kvs ...params.KV) *params.ExtractorArgsBuilder {
+	for _, kv := range kvs {
+		b.Set("generic", kv)
+	}
+	return b
+}
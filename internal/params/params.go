@@ -1,56 +1,11 @@
 package params
 
 import (
-	"fmt"
 	"log"
 	"regexp"
 	"strings"
 )
 
-// dangerousFlags are yt-dlp flags that can execute arbitrary commands or read/write arbitrary files.
-var dangerousFlags = map[string]bool{
-	"--exec":                  true,
-	"--exec-before-download":  true,
-	"--batch-file":            true,
-	"--config-location":       true,
-	"--config-locations":      true,
-	"--cookies":               true,
-	"--cookies-from-browser":  true,
-	"--download-archive":      true,
-	"--print-to-file":         true,
-	"--output-na-placeholder": true,
-	"--postprocessor-args":    true,
-	"--ppa":                   true,
-}
-
-// SanitizeArgs removes dangerous flags and their values from an argument list.
-// Returns sanitized args and an error if dangerous flags were found.
-func SanitizeArgs(args []string) ([]string, error) {
-	var clean []string
-	var blocked []string
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		// Check exact match or --flag=value form
-		flagName := arg
-		if idx := strings.Index(arg, "="); idx > 0 {
-			flagName = arg[:idx]
-		}
-		if dangerousFlags[flagName] {
-			blocked = append(blocked, flagName)
-			// Skip the next token if it's a separate value (not --flag=value)
-			if !strings.Contains(arg, "=") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-			}
-			continue
-		}
-		clean = append(clean, arg)
-	}
-	if len(blocked) > 0 {
-		return clean, fmt.Errorf("blocked dangerous flags: %s", strings.Join(blocked, ", "))
-	}
-	return clean, nil
-}
-
 // DownloadRequest represents what the frontend sends.
 type DownloadRequest struct {
 	URL  string `json:"url" form:"url"`
@@ -72,7 +27,17 @@ type DownloadRequest struct {
 	ConcFrags     string `json:"conc_frags" form:"conc_frags"` // concurrent fragments
 	OutputTmpl    string `json:"output_tmpl" form:"output_tmpl"`
 	ExtractorArgs string `json:"extractor_args" form:"extractor_args"`
-	CookiesFrom   string `json:"cookies_from" form:"cookies_from"`
+	// ExtractorArgsBuilder is the structured alternative to ExtractorArgs: when
+	// set, BuildArgs renders it (merged with config.DefaultArgs' youtube
+	// preset) instead of passing the raw string through.
+	ExtractorArgsBuilder *ExtractorArgsBuilder `json:"extractor_args_builder" form:"-"`
+	CookiesFrom          string                `json:"cookies_from" form:"cookies_from"`
+	// CookiesJar references an uploaded cookies.Store jar by opaque ID — never
+	// a filesystem path, so a request can never point yt-dlp at an arbitrary
+	// server file. The handler resolves it via cookies.Store.Path and calls
+	// SetCookiesPath before BuildArgs runs.
+	CookiesJar    string `json:"cookies_jar" form:"cookies_jar"`
+	cookiesPath   string `json:"-"`
 	Username      string `json:"username" form:"username"`
 	Password      string `json:"password" form:"password"`
 	NoPlaylist    bool   `json:"no_playlist" form:"no_playlist"`
@@ -87,6 +52,22 @@ type DownloadRequest struct {
 	PPArgs        string `json:"pp_args" form:"pp_args"`
 	SleepInterval string `json:"sleep_interval" form:"sleep_interval"`
 	MaxSleep      string `json:"max_sleep" form:"max_sleep"`
+
+	// InfoOnly requests metadata extraction instead of a real download: BuildArgs
+	// injects --dump-single-json --no-download --skip-download --flat-playlist.
+	// See the metadata package for parsing the resulting JSON.
+	InfoOnly bool `json:"info_only" form:"info_only"`
+
+	// FormatSelector, when set, takes precedence over the raw Format string:
+	// BuildArgs renders it into --format/--format-sort instead.
+	FormatSelector *FormatSelector `json:"format_selector" form:"-"`
+}
+
+// SetCookiesPath records the on-disk path a previously-uploaded CookiesJar
+// resolved to. Only the handler that owns a cookies.Store should call this —
+// the path is trusted, unlike anything else on DownloadRequest.
+func (r *DownloadRequest) SetCookiesPath(path string) {
+	r.cookiesPath = path
 }
 
 var shellRe = regexp.MustCompile(`"([^"]*)"|'([^']*)'|(\S+)`)
@@ -161,7 +142,30 @@ func buildRaw(req *DownloadRequest) (string, []string) {
 func buildUI(req *DownloadRequest) []string {
 	var a []string
 
-	if req.Format != "" {
+	if req.InfoOnly {
+		a = append(a, "--dump-single-json", "--no-download", "--skip-download", "--flat-playlist")
+	}
+
+	if req.FormatSelector != nil {
+		if err := req.FormatSelector.Validate(); err != nil {
+			log.Printf("[params] format selector: %v", err)
+		} else {
+			a = append(a, "--format", req.FormatSelector.Build())
+			if sort := req.FormatSelector.FormatSort(); sort != "" {
+				a = append(a, "--format-sort", sort)
+			}
+			if req.FormatSelector.PreferFreeFormats {
+				a = append(a, "--prefer-free-formats")
+			}
+			// Build() can pick separate bestvideo/bestaudio streams that need
+			// remuxing into Container; --merge-output-format is what actually
+			// makes that happen (otherwise yt-dlp muxes into mkv regardless).
+			// An explicit req.MergeFormat always wins if both are set.
+			if req.FormatSelector.Container != "" && req.MergeFormat == "" {
+				req.MergeFormat = req.FormatSelector.Container
+			}
+		}
+	} else if req.Format != "" {
 		a = append(a, "--format", req.Format)
 	}
 	if req.AudioOnly {
@@ -206,14 +210,30 @@ func buildUI(req *DownloadRequest) []string {
 	} else if req.SponsorBlock == "remove" {
 		a = append(a, "--sponsorblock-remove", "all")
 	}
+	// These map 1:1 onto policy-governed flags but never pass through
+	// SanitizeArgs (that only runs on raw-mode/extra-args tokens), so they're
+	// validated here directly against the same rules instead of going
+	// straight to the command line unchecked.
 	if req.Proxy != "" {
-		a = append(a, "--proxy", req.Proxy)
+		if err := ValidateValue("--proxy", req.Proxy); err != nil {
+			log.Printf("[params] proxy: %v", err)
+		} else {
+			a = append(a, "--proxy", req.Proxy)
+		}
 	}
 	if req.RateLimit != "" {
-		a = append(a, "--limit-rate", req.RateLimit)
+		if err := ValidateValue("--limit-rate", req.RateLimit); err != nil {
+			log.Printf("[params] rate_limit: %v", err)
+		} else {
+			a = append(a, "--limit-rate", req.RateLimit)
+		}
 	}
 	if req.ConcFrags != "" {
-		a = append(a, "--concurrent-fragments", req.ConcFrags)
+		if err := ValidateValue("--concurrent-fragments", req.ConcFrags); err != nil {
+			log.Printf("[params] conc_frags: %v", err)
+		} else {
+			a = append(a, "--concurrent-fragments", req.ConcFrags)
+		}
 	}
 	if req.OutputTmpl != "" {
 		// Block path traversal, absolute paths (Unix & Windows), UNC paths, and drive-relative paths
@@ -229,7 +249,24 @@ func buildUI(req *DownloadRequest) []string {
 			a = append(a, "-o", tmpl)
 		}
 	}
-	if req.ExtractorArgs != "" {
+	if req.ExtractorArgsBuilder != nil && !req.ExtractorArgsBuilder.Empty() {
+		// Merge with the youtube default config.DefaultArgs hardcodes, so a
+		// user-supplied youtube preset doesn't silently lose android/web clients.
+		// One KV per client, since Build escapes a "," inside a single value.
+		b := req.ExtractorArgsBuilder
+		hasClient := false
+		for _, kv := range b.args["youtube"] {
+			if kv.Key == "player_client" {
+				hasClient = true
+				break
+			}
+		}
+		if !hasClient {
+			b.Set("youtube", KV{Key: "player_client", Value: "android"}).
+				Set("youtube", KV{Key: "player_client", Value: "web"})
+		}
+		a = append(a, b.Build()...)
+	} else if req.ExtractorArgs != "" {
 		a = append(a, "--extractor-args", req.ExtractorArgs)
 	}
 	// --cookies-from-browser is blocked for security (exposes server browser cookies)
@@ -248,7 +285,11 @@ func buildUI(req *DownloadRequest) []string {
 		a = append(a, "--playlist-items", req.PlaylistItems)
 	}
 	if req.MergeFormat != "" {
-		a = append(a, "--merge-output-format", req.MergeFormat)
+		if err := ValidateValue("--merge-output-format", req.MergeFormat); err != nil {
+			log.Printf("[params] merge_format: %v", err)
+		} else {
+			a = append(a, "--merge-output-format", req.MergeFormat)
+		}
 	}
 	if req.RemuxVideo != "" {
 		a = append(a, "--remux-video", req.RemuxVideo)
@@ -274,5 +315,12 @@ func buildUI(req *DownloadRequest) []string {
 		a = append(a, extra...)
 	}
 
+	// Injected after sanitization so the resolved, validated jar path can
+	// never be blocked or confused with a user-supplied --cookies value
+	// (--cookies stays denied by the default policy for raw/extra args).
+	if req.cookiesPath != "" {
+		a = append(a, "--cookies", req.cookiesPath)
+	}
+
 	return a
 }
@@ -0,0 +1,353 @@
+package params
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tier is the enforcement level a Policy assigns to a yt-dlp flag.
+type Tier string
+
+const (
+	// TierDeny strips the flag (and its value) unconditionally.
+	TierDeny Tier = "deny"
+	// TierRequireApproval strips the flag unless an operator has explicitly
+	// allowed it for this deployment (e.g. single-tenant servers allowing --cookies).
+	TierRequireApproval Tier = "require-approval"
+	// TierAllow passes the flag through, subject to its Validator if any.
+	TierAllow Tier = "allow"
+)
+
+// Validator checks a flag's value, returning a human-readable error if invalid.
+type Validator func(value string) error
+
+// FlagRule is one flag's policy: its tier and, for TierAllow flags, an
+// optional value validator.
+type FlagRule struct {
+	Tier      Tier
+	Validator Validator `yaml:"-"`
+}
+
+// PolicyViolation describes a single flag that a Policy rejected, so the API
+// layer can surface which flag failed and why instead of a joined string.
+type PolicyViolation struct {
+	Flag   string
+	Tier   Tier
+	Reason string
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("flag %s blocked (%s): %s", v.Flag, v.Tier, v.Reason)
+}
+
+// Policy is a set of per-flag rules governing which yt-dlp flags a request
+// may pass through. It replaces the old hardcoded dangerousFlags denylist
+// with three tiers operators can tune per deployment.
+type Policy struct {
+	rules map[string]FlagRule
+}
+
+// policyFile is the on-disk (YAML) representation of a Policy.
+type policyFile struct {
+	Tiers struct {
+		Deny            []string `yaml:"deny"`
+		RequireApproval []string `yaml:"require-approval"`
+		Allow           []string `yaml:"allow"`
+	} `yaml:"tiers"`
+	Validators map[string]string `yaml:"validators"`
+}
+
+// builtinValidators maps a validator name (as used in policy.yaml) to its
+// implementation. Keep this in sync with the documented validator syntax.
+func builtinValidators() map[string]func(arg string) Validator {
+	return map[string]func(arg string) Validator{
+		"proxy": func(_ string) Validator {
+			re := regexp.MustCompile(`^(https?|socks[45]h?)://[^\s]+$`)
+			return func(v string) error {
+				if !re.MatchString(v) {
+					return fmt.Errorf("must be a http(s)/socks4/socks5 URL")
+				}
+				return nil
+			}
+		},
+		"rate": func(_ string) Validator {
+			re := regexp.MustCompile(`^[\d.]+[KkMmGg]?$`)
+			return func(v string) error {
+				if !re.MatchString(v) {
+					return fmt.Errorf("must be a size like 500K, 4.2M")
+				}
+				return nil
+			}
+		},
+	}
+}
+
+// intRangeValidator parses a "int-range:MIN:MAX" validator spec.
+func intRangeValidator(spec string) (Validator, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("int-range validator wants \"int-range:MIN:MAX\", got %q", spec)
+	}
+	min, err1 := strconv.Atoi(parts[1])
+	max, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("int-range bounds must be integers: %q", spec)
+	}
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		if n < min || n > max {
+			return fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return nil
+	}, nil
+}
+
+// allowlistValidator parses an "allowlist:a,b,c" validator spec.
+func allowlistValidator(spec string) (Validator, error) {
+	const prefix = "allowlist:"
+	if !strings.HasPrefix(spec, prefix) {
+		return nil, fmt.Errorf("allowlist validator wants %q prefix, got %q", prefix, spec)
+	}
+	values := strings.Split(strings.TrimPrefix(spec, prefix), ",")
+	return func(v string) error {
+		for _, allowed := range values {
+			if v == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", strings.Join(values, ", "))
+	}, nil
+}
+
+// parseValidator resolves a validator spec string from policy.yaml into a Validator func.
+func parseValidator(flag, spec string) (Validator, error) {
+	switch {
+	case spec == "proxy":
+		return builtinValidators()["proxy"](flag), nil
+	case spec == "rate":
+		return builtinValidators()["rate"](flag), nil
+	case strings.HasPrefix(spec, "int-range:"):
+		return intRangeValidator(spec)
+	case strings.HasPrefix(spec, "allowlist:"):
+		return allowlistValidator(spec)
+	case strings.HasPrefix(spec, "regex:"):
+		re, err := regexp.Compile(strings.TrimPrefix(spec, "regex:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex validator for %s: %w", flag, err)
+		}
+		return func(v string) error {
+			if !re.MatchString(v) {
+				return fmt.Errorf("does not match %s", re.String())
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown validator spec %q for flag %s", spec, flag)
+	}
+}
+
+// DefaultPolicy returns a policy that denies the same flags the original
+// hardcoded dangerousFlags map did (everything that can execute commands
+// or touch arbitrary files) and, beyond that map, validates the values of
+// --proxy, --limit-rate, --concurrent-fragments and --merge-output-format.
+func DefaultPolicy() *Policy {
+	p := &Policy{rules: make(map[string]FlagRule)}
+	for _, flag := range []string{
+		"--exec",
+		"--exec-before-download",
+		"--batch-file",
+		"--config-location",
+		"--config-locations",
+		"--cookies",
+		"--cookies-from-browser",
+		"--download-archive",
+		"--print-to-file",
+		"--output-na-placeholder",
+		"--postprocessor-args",
+		"--ppa",
+	} {
+		p.rules[flag] = FlagRule{Tier: TierDeny}
+	}
+	// Common, useful flags ship pre-validated so an operator loosening the
+	// policy doesn't also have to hand-write these regexes.
+	p.rules["--proxy"] = FlagRule{Tier: TierAllow, Validator: builtinValidators()["proxy"]("--proxy")}
+	p.rules["--limit-rate"] = FlagRule{Tier: TierAllow, Validator: builtinValidators()["rate"]("--limit-rate")}
+	if v, err := intRangeValidator("int-range:1:16"); err == nil {
+		p.rules["--concurrent-fragments"] = FlagRule{Tier: TierAllow, Validator: v}
+	}
+	if v, err := allowlistValidator("allowlist:mp4,mkv,webm"); err == nil {
+		p.rules["--merge-output-format"] = FlagRule{Tier: TierAllow, Validator: v}
+	}
+	return p
+}
+
+// LoadPolicy loads a Policy from ConfigDir/policy.yaml, falling back to
+// DefaultPolicy when the file does not exist. Flags not mentioned in the
+// file default to TierAllow with no validator, matching yt-dlp's own surface
+// (the policy only needs to describe restrictions and validators, not every flag).
+func LoadPolicy(configDir string) (*Policy, error) {
+	path := configDir + "/policy.yaml"
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultPolicy(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("params: read policy file: %w", err)
+	}
+
+	var pf policyFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("params: parse policy file: %w", err)
+	}
+
+	p := &Policy{rules: make(map[string]FlagRule)}
+	for _, flag := range pf.Tiers.Deny {
+		p.rules[flag] = FlagRule{Tier: TierDeny}
+	}
+	for _, flag := range pf.Tiers.RequireApproval {
+		p.rules[flag] = FlagRule{Tier: TierRequireApproval}
+	}
+	for _, flag := range pf.Tiers.Allow {
+		p.rules[flag] = FlagRule{Tier: TierAllow}
+	}
+	for flag, spec := range pf.Validators {
+		v, err := parseValidator(flag, spec)
+		if err != nil {
+			return nil, err
+		}
+		rule := p.rules[flag]
+		if rule.Tier == "" {
+			rule.Tier = TierAllow
+		}
+		rule.Validator = v
+		p.rules[flag] = rule
+	}
+	return p, nil
+}
+
+// ruleFor returns the FlagRule for flag, defaulting to an unrestricted
+// TierAllow when the policy has no opinion on it.
+func (p *Policy) ruleFor(flag string) FlagRule {
+	if rule, ok := p.rules[flag]; ok {
+		return rule
+	}
+	return FlagRule{Tier: TierAllow}
+}
+
+// Sanitize filters args against the policy, returning the clean args plus
+// one PolicyViolation per blocked/invalid flag.
+func (p *Policy) Sanitize(args []string) ([]string, []PolicyViolation) {
+	var clean []string
+	var violations []PolicyViolation
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		flagName := arg
+		value := ""
+		hasInlineValue := false
+		if idx := strings.Index(arg, "="); idx > 0 {
+			flagName = arg[:idx]
+			value = arg[idx+1:]
+			hasInlineValue = true
+		} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			value = args[i+1]
+		}
+
+		rule := p.ruleFor(flagName)
+		switch rule.Tier {
+		case TierDeny, TierRequireApproval:
+			violations = append(violations, PolicyViolation{
+				Flag: flagName, Tier: rule.Tier,
+				Reason: string(rule.Tier) + " flag",
+			})
+			if !hasInlineValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+			}
+			continue
+		case TierAllow:
+			if rule.Validator != nil && value != "" {
+				if err := rule.Validator(value); err != nil {
+					violations = append(violations, PolicyViolation{
+						Flag: flagName, Tier: rule.Tier, Reason: err.Error(),
+					})
+					if !hasInlineValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+						i++
+					}
+					continue
+				}
+			}
+		}
+		clean = append(clean, arg)
+	}
+	return clean, violations
+}
+
+// Explain is a dry-run of Sanitize for the UI: it reports a PolicyViolation
+// for every flag Sanitize would strip, without changing args. Flags that
+// pass are not listed.
+func (p *Policy) Explain(args []string) []PolicyViolation {
+	_, violations := p.Sanitize(args)
+	return violations
+}
+
+// ValidateValue checks value against flag's policy rule (tier + validator),
+// for structured DownloadRequest fields (Proxy, RateLimit, ConcFrags,
+// MergeFormat, ...) that map 1:1 onto a single policy-governed flag and so
+// bypass Sanitize's raw-args flag/value parsing entirely.
+func (p *Policy) ValidateValue(flag, value string) error {
+	rule := p.ruleFor(flag)
+	switch rule.Tier {
+	case TierDeny, TierRequireApproval:
+		return fmt.Errorf("%s flag %s blocked", rule.Tier, flag)
+	}
+	if rule.Validator != nil {
+		return rule.Validator(value)
+	}
+	return nil
+}
+
+// defaultPolicy backs the package-level SanitizeArgs for callers that don't
+// (yet) load a per-deployment Policy via LoadPolicy.
+var defaultPolicy = DefaultPolicy()
+
+// SetDefaultPolicy installs p as the policy SanitizeArgs enforces. Call this
+// once at startup after LoadPolicy(cfg.ConfigDir) to apply an operator's
+// policy.yaml instead of DefaultPolicy.
+func SetDefaultPolicy(p *Policy) {
+	defaultPolicy = p
+}
+
+// Explain is a dry-run of SanitizeArgs against the default policy, for UI
+// surfaces that want to warn a user before they submit a blocked flag.
+func Explain(args []string) []PolicyViolation {
+	return defaultPolicy.Explain(args)
+}
+
+// ValidateValue checks value against the default policy's rule for flag. See
+// Policy.ValidateValue.
+func ValidateValue(flag, value string) error {
+	return defaultPolicy.ValidateValue(flag, value)
+}
+
+// SanitizeArgs removes policy-denied flags and their values from an argument
+// list using the default policy. Returns sanitized args and a joined error
+// if any flags were blocked. Kept for callers that don't need a custom Policy;
+// prefer Policy.Sanitize directly when you have a loaded Policy.
+func SanitizeArgs(args []string) ([]string, error) {
+	clean, violations := defaultPolicy.Sanitize(args)
+	if len(violations) == 0 {
+		return clean, nil
+	}
+	names := make([]string, len(violations))
+	for i, v := range violations {
+		names[i] = v.Flag
+	}
+	return clean, fmt.Errorf("blocked dangerous flags: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,81 @@
+package params
+
+import "testing"
+
+func TestPolicySanitizeDeniesDangerousFlags(t *testing.T) {
+	p := DefaultPolicy()
+	clean, violations := p.Sanitize([]string{"--exec", "rm -rf /", "--format", "best"})
+	if len(violations) != 1 || violations[0].Flag != "--exec" {
+		t.Fatalf("Sanitize() violations = %+v, want one --exec violation", violations)
+	}
+	want := []string{"--format", "best"}
+	if len(clean) != len(want) || clean[0] != want[0] || clean[1] != want[1] {
+		t.Errorf("Sanitize() clean = %v, want %v", clean, want)
+	}
+}
+
+func TestPolicySanitizeValidatesAllowedFlags(t *testing.T) {
+	p := DefaultPolicy()
+	_, violations := p.Sanitize([]string{"--proxy", "not-a-url"})
+	if len(violations) != 1 || violations[0].Flag != "--proxy" {
+		t.Fatalf("Sanitize() violations = %+v, want one --proxy violation", violations)
+	}
+
+	clean, violations := p.Sanitize([]string{"--proxy", "socks5://127.0.0.1:1080"})
+	if len(violations) != 0 {
+		t.Fatalf("Sanitize() violations = %+v, want none for a valid proxy URL", violations)
+	}
+	if len(clean) != 2 {
+		t.Errorf("Sanitize() clean = %v, want the proxy flag passed through", clean)
+	}
+}
+
+func TestPolicyExplainIsDryRun(t *testing.T) {
+	p := DefaultPolicy()
+	args := []string{"--exec", "echo hi"}
+	violations := p.Explain(args)
+	if len(violations) != 1 {
+		t.Fatalf("Explain() violations = %+v, want one", violations)
+	}
+	if len(args) != 2 {
+		t.Errorf("Explain() mutated its input args to %v", args)
+	}
+}
+
+func TestPolicyValidateValue(t *testing.T) {
+	p := DefaultPolicy()
+	if err := p.ValidateValue("--exec", "echo hi"); err == nil {
+		t.Error("ValidateValue(--exec) = nil, want an error for a denied flag")
+	}
+	if err := p.ValidateValue("--limit-rate", "not-a-rate"); err == nil {
+		t.Error("ValidateValue(--limit-rate, \"not-a-rate\") = nil, want a validation error")
+	}
+	if err := p.ValidateValue("--limit-rate", "500K"); err != nil {
+		t.Errorf("ValidateValue(--limit-rate, \"500K\") = %v, want nil", err)
+	}
+	if err := p.ValidateValue("--merge-output-format", "avi"); err == nil {
+		t.Error("ValidateValue(--merge-output-format, \"avi\") = nil, want an error (not in allowlist)")
+	}
+	if err := p.ValidateValue("--unrestricted-flag", "anything"); err != nil {
+		t.Errorf("ValidateValue for an unmentioned flag = %v, want nil", err)
+	}
+}
+
+func TestIntRangeValidator(t *testing.T) {
+	v, err := intRangeValidator("int-range:1:16")
+	if err != nil {
+		t.Fatalf("intRangeValidator() error = %v", err)
+	}
+	if err := v("0"); err == nil {
+		t.Error("intRangeValidator: 0 should be out of range")
+	}
+	if err := v("17"); err == nil {
+		t.Error("intRangeValidator: 17 should be out of range")
+	}
+	if err := v("8"); err != nil {
+		t.Errorf("intRangeValidator: 8 should be in range, got %v", err)
+	}
+	if _, err := intRangeValidator("int-range:bad:16"); err == nil {
+		t.Error("intRangeValidator: non-integer bound should error")
+	}
+}
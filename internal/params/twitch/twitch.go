@@ -0,0 +1,19 @@
+// Package twitch provides params.ExtractorArgsBuilder presets for the
+// twitch extractor.
+package twitch
+
+import "yt-dlp-web/internal/params"
+
+// ClientID overrides the OAuth client ID twitch-dl uses, needed when the
+// default embedded client ID gets rate-limited or revoked upstream.
+func ClientID(id string) params.KV {
+	return params.KV{Key: "client_id", Value: id}
+}
+
+// Apply sets the given twitch extractor args on b.
+func Apply(b *params.ExtractorArgsBuilder, kvs ...params.KV) *params.ExtractorArgsBuilder {
+	for _, kv := range kvs {
+		b.Set("twitch", kv)
+	}
+	return b
+}
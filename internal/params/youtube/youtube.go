@@ -0,0 +1,32 @@
+// Package youtube provides params.ExtractorArgsBuilder presets for the
+// youtube extractor's most commonly tuned options.
+package youtube
+
+import (
+	"yt-dlp-web/internal/params"
+)
+
+// PlayerClients builds one `player_client` extractor arg per client, which
+// ExtractorArgsBuilder.Build joins into one list, e.g.
+// Apply(b, PlayerClients("android", "web")...) -> player_client=android,web.
+func PlayerClients(clients ...string) []params.KV {
+	kvs := make([]params.KV, 0, len(clients))
+	for _, c := range clients {
+		kvs = append(kvs, params.KV{Key: "player_client", Value: c})
+	}
+	return kvs
+}
+
+// POToken builds the `po_token` extractor arg used to bypass YouTube's
+// proof-of-origin checks, e.g. POToken("web.gvs+XXXX").
+func POToken(token string) params.KV {
+	return params.KV{Key: "po_token", Value: token}
+}
+
+// Apply sets the given youtube extractor args on b.
+func Apply(b *params.ExtractorArgsBuilder, kvs ...params.KV) *params.ExtractorArgsBuilder {
+	for _, kv := range kvs {
+		b.Set("youtube", kv)
+	}
+	return b
+}
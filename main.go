@@ -5,24 +5,35 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"yt-dlp-web/internal/config"
+	"yt-dlp-web/internal/cookies"
 	"yt-dlp-web/internal/download"
 	"yt-dlp-web/internal/handler"
+	"yt-dlp-web/internal/logging"
+	"yt-dlp-web/internal/metrics"
+	"yt-dlp-web/internal/params"
 
+	"github.com/getsentry/sentry-go"
+	"github.com/gofiber/contrib/fibersentry"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/etag"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 //go:embed static/*
@@ -49,6 +60,7 @@ func main() {
 	}
 
 	// --- Web server mode ---
+	applog := logging.Named("main")
 	cfg := config.Load()
 
 	// Sanity check for yt-dlp existence to prevent silent failing downloads
@@ -65,32 +77,69 @@ func main() {
 	}
 
 	if err := checkYtDlp(cfg.YtDlpPath); err != nil {
-		log.Printf("=============================================================================")
-		log.Printf("FATAL: %v", err)
-		log.Printf("=============================================================================")
+		applog.Error("yt-dlp not found", "error", err)
 		// On Android, we want this error to be visible to the user
 		// The Go server will still start, but downloads will fail with clear error
 	}
 
+	if policy, err := params.LoadPolicy(cfg.ConfigDir); err != nil {
+		applog.Warn("failed to load policy.yaml, keeping default policy", "config_dir", cfg.ConfigDir, "error", err)
+	} else {
+		params.SetDefaultPolicy(policy)
+	}
+
+	// Optional error reporting via SENTRY_DSN; sentry.CaptureException/
+	// AddBreadcrumb calls elsewhere (download.Manager, /ws) are always safe
+	// to make — the SDK just stays disabled and drops them when Init wasn't
+	// called with a DSN.
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		if err := sentry.Init(sentry.ClientOptions{Dsn: dsn}); err != nil {
+			applog.Error("failed to initialize sentry", "error", err)
+		} else {
+			defer sentry.Flush(2 * time.Second)
+		}
+	}
+
 	hub := handler.NewHub()
+	mtr := metrics.New()
 
 	mgr := download.NewManager(cfg, func(t *download.Task) {
 		hub.BroadcastTask(t)
-	})
+	}, func(b download.Bandwidth) {
+		hub.BroadcastBandwidth(b)
+	}, mtr)
 
-	api := handler.NewAPI(mgr)
+	apiKey := os.Getenv("API_KEY")
+	api := handler.NewAPI(mgr, cookies.NewStore(cfg.ConfigDir), cfg, apiKey != "")
 
+	// With the trusted-proxy check off, fiber reads ProxyHeader from any
+	// peer, so X-Forwarded-For is only consulted when TRUSTED_PROXIES is set.
+	proxyHeader := ""
+	if len(cfg.TrustedProxies) > 0 {
+		proxyHeader = fiber.HeaderXForwardedFor
+	}
 	app := fiber.New(fiber.Config{
-		AppName:               "yt-dlp-web",
-		DisableStartupMessage: true,
-		BodyLimit:             10 * 1024 * 1024,
+		AppName:                 "yt-dlp-web",
+		DisableStartupMessage:   true,
+		BodyLimit:               10 * 1024 * 1024,
+		EnableTrustedProxyCheck: len(cfg.TrustedProxies) > 0,
+		TrustedProxies:          cfg.TrustedProxies,
+		ProxyHeader:             proxyHeader,
 	})
 
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format:     "${time} ${status} ${method} ${path} ${latency}\n",
-		TimeFormat: "15:04:05",
-	}))
+	app.Use(fibersentry.New(fibersentry.Config{Repanic: true}))
+	app.Use(requestid.New())
+	app.Use(httpLogMiddleware(logging.Named("http")))
+	// /downloads serves byte-range (206 Content-Range) responses — etag
+	// and, worse, compress would recompute/recompress a body whose headers
+	// already describe a specific uncompressed byte range, corrupting
+	// exactly the resumable/seekable download chunk2-2 added. Both skip it.
+	skipDownloads := func(c *fiber.Ctx) bool {
+		return strings.HasPrefix(c.Path(), "/downloads") || strings.HasPrefix(c.Path(), "/api/download/")
+	}
+	app.Use(etag.New(etag.Config{Next: skipDownloads}))
+	app.Use(compress.New(compress.Config{Next: skipDownloads}))
 	corsOrigins := os.Getenv("CORS_ORIGINS")
 	if corsOrigins == "" {
 		corsOrigins = fmt.Sprintf("http://localhost:%s, http://127.0.0.1:%s", cfg.Port, cfg.Port)
@@ -102,13 +151,15 @@ func main() {
 		AllowCredentials: false,
 	}))
 
-	// Optional API key authentication via API_KEY env var
-	apiKey := os.Getenv("API_KEY")
+	// Optional API key authentication via API_KEY env var. Protects the API,
+	// the WebSocket, and /metrics (it's operational data, same sensitivity
+	// as the task list) — everything else (health check, static files) is
+	// public so a load balancer or the UI shell can always reach them.
 	if apiKey != "" {
 		app.Use(func(c *fiber.Ctx) error {
-			// Skip auth for health check and static files
 			p := c.Path()
-			if p == "/health" || (!strings.HasPrefix(p, "/api/") && !strings.HasPrefix(p, "/ws")) {
+			protected := strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/ws") || p == "/metrics"
+			if !protected {
 				return c.Next()
 			}
 			if subtle.ConstantTimeCompare([]byte(c.Get("X-API-Key")), []byte(apiKey)) == 1 {
@@ -118,11 +169,60 @@ func main() {
 		})
 	}
 
-	// Health check
-	app.Get("/health", func(c *fiber.Ctx) error {
+	// /livez: is the process itself alive — always 200 until the graceful
+	// shutdown sequence below has started, never based on any dependency's
+	// health (that's /readyz's job), so a flaky yt-dlp binary can't get the
+	// whole pod killed and restarted by a liveness probe.
+	app.Get("/livez", func(c *fiber.Ctx) error {
+		if mgr.ShuttingDown() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "shutting down"})
+		}
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
 
+	// /readyz: should a load balancer send this instance traffic right now.
+	// Flips to 503 the moment graceful shutdown begins (before app.Shutdown
+	// actually stops the listener) so a LB drains connections cleanly, and
+	// also fails if yt-dlp isn't resolvable, the download dir isn't
+	// writable, or the worker queues are full (combined depth at combined capacity).
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		checks := fiber.Map{}
+		ready := true
+
+		if mgr.ShuttingDown() {
+			checks["shutting_down"] = true
+			ready = false
+		}
+
+		if err := checkYtDlp(cfg.YtDlpPath); err != nil {
+			checks["ytdlp"] = err.Error()
+			ready = false
+		} else {
+			checks["ytdlp"] = "ok"
+		}
+
+		if err := checkWritable(cfg.DownloadDir); err != nil {
+			checks["download_dir"] = err.Error()
+			ready = false
+		} else {
+			checks["download_dir"] = "ok"
+		}
+
+		depth, capacity := mgr.QueueDepth(), mgr.QueueCapacity()
+		checks["queue"] = fmt.Sprintf("%d/%d", depth, capacity)
+		if capacity > 0 && depth >= capacity {
+			ready = false
+		}
+
+		if !ready {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "not ready", "checks": checks})
+		}
+		return c.JSON(fiber.Map{"status": "ok", "checks": checks})
+	})
+
+	// Prometheus metrics
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(mtr.Registry, promhttp.HandlerOpts{})))
+
 	// WebSocket — must have upgrade check middleware
 	app.Use("/ws", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
@@ -135,30 +235,43 @@ func main() {
 		defer hub.Unregister(c)
 		for {
 			if _, _, err := c.ReadMessage(); err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					sentry.CaptureException(err)
+				}
 				break
 			}
 		}
 	}))
 
+	// Downloaded files — ByteRange enables Range/seek support directly in
+	// fasthttp's static handler, for browser video preview and resumable
+	// client downloads of files still being written to (a partial file just
+	// serves however many bytes exist so far).
+	app.Static("/downloads", cfg.DownloadDir, fiber.Static{ByteRange: true})
+
 	// API routes
 	api.RegisterRoutes(app)
 
 	// Static files — use embedded FS by default, filesystem if STATIC_DIR is set
+	staticMaxAge := int(cfg.StaticCacheMaxAge.Seconds())
 	if os.Getenv("STATIC_DIR") != "" {
 		app.Static("/", cfg.StaticDir, fiber.Static{
 			Compress: true,
 			Index:    "index.html",
+			MaxAge:   staticMaxAge,
 		})
 	} else {
 		subFS, err := fs.Sub(staticFS, "static")
 		if err != nil {
-			log.Fatalf("failed to load embedded static files: %v", err)
+			applog.Error("failed to load embedded static files", "error", err)
+			os.Exit(1)
 		}
 		app.Use("/", filesystem.New(filesystem.Config{
 			Root:         http.FS(subFS),
 			Browse:       false,
 			Index:        "index.html",
 			NotFoundFile: "index.html",
+			MaxAge:       staticMaxAge,
 		}))
 	}
 
@@ -167,14 +280,47 @@ func main() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 		<-quit
-		log.Println("Shutting down...")
+		applog.Info("shutting down")
 		mgr.Shutdown()
 		_ = app.Shutdown()
 	}()
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("yt-dlp-web listening on http://0.0.0.0%s", addr)
+	applog.Info("listening", "addr", fmt.Sprintf("http://0.0.0.0%s", addr))
 	if err := app.Listen(addr); err != nil {
-		log.Fatalf("server error: %v", err)
+		applog.Error("server error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// checkWritable probes dir by creating and removing a throwaway temp file,
+// for /readyz to catch a download directory that's gone read-only (full
+// disk, revoked permissions) before a submission fails because of it.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// httpLogMiddleware logs each request's method, path, status, latency, and
+// request ID (see requestid.New above) at Info level, replacing fiber's
+// logger.New string-format middleware now that request/task logs share one
+// structured sink.
+func httpLogMiddleware(log *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		log.Info("request",
+			"request_id", fmt.Sprint(c.Locals("requestid")),
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", c.Response().StatusCode(),
+			"latency_ms", time.Since(start).Milliseconds(),
+		)
+		return err
 	}
 }